@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var capabilitiesCmd = &cobra.Command{
+	Use:   "capabilities",
+	Short: "Export the catalog of capabilities declared by each component as JSON",
+	Run: func(cmd *cobra.Command, args []string) {
+		reg, err := newComponentRegistry()
+		if err != nil {
+			log.WithError(err).Fatal("could not load component registry")
+		}
+
+		out := os.Stdout
+		if capabilitiesFlags.outputFile != "" {
+			out, err = os.Create(capabilitiesFlags.outputFile)
+			if err != nil {
+				log.WithError(err).Fatal("could not create output file")
+			}
+			defer out.Close()
+		}
+
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reg.CapabilityCatalog()); err != nil {
+			log.WithError(err).Fatal("could not write capability catalog")
+		}
+	},
+}
+
+type CapabilitiesFlags struct {
+	outputFile string
+}
+
+var capabilitiesFlags = NewCapabilitiesFlags()
+
+func NewCapabilitiesFlags() *CapabilitiesFlags {
+	return &CapabilitiesFlags{}
+}
+
+func (f *CapabilitiesFlags) BindFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.outputFile, "output", "", "File to write the catalog to (default stdout)")
+}
+
+func init() {
+	capabilitiesFlags.BindFlags(capabilitiesCmd.Flags())
+	rootCmd.AddCommand(capabilitiesCmd)
+}
@@ -5,9 +5,12 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
 )
 
 var logLevel string
+var componentsDir string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -34,4 +37,23 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info",
 		"Log level (trace,debug,info,warn,error) (default info)")
+	rootCmd.PersistentFlags().StringVar(&componentsDir, "components-dir", "",
+		"Directory of YAML or JSON component definitions to register in addition to the built-in components")
+}
+
+// newComponentRegistry returns the registry of built-in components, plus any
+// declarative components found in --components-dir.
+func newComponentRegistry() (*registry.Registry, error) {
+	reg := registry.NewComponentRegistry()
+	if componentsDir != "" {
+		if err := reg.LoadDirectory(componentsDir); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := reg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return reg, nil
 }
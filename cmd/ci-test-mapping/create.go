@@ -15,8 +15,6 @@ import (
 	"github.com/spf13/cobra"
 
 	"k8s.io/apimachinery/pkg/util/sets"
-
-	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
 )
 
 type CreateFlags struct {
@@ -45,7 +43,10 @@ var createCmd = &cobra.Command{
 			logrus.WithError(err).Fatal("could not fetch jira components")
 		}
 
-		reg := registry.NewComponentRegistry()
+		reg, err := newComponentRegistry()
+		if err != nil {
+			logrus.WithError(err).Fatal("could not load component registry")
+		}
 		knownJiraComponents := sets.New[string]()
 		for _, c := range reg.Components {
 			knownJiraComponents.Insert(c.JiraComponents()...)
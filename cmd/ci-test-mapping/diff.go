@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/openshift-eng/ci-test-mapping/cmd/ci-test-mapping/flags"
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/mapping"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare two mapping runs and report which tests changed ownership",
+	Run: func(cmd *cobra.Command, args []string) {
+		if (diffFlags.oldFile == "") == !diffFlags.oldFromBigQuery {
+			cmd.Usage() //nolint:errcheck
+			log.Fatal("please supply exactly one of --old or --old-from-bigquery")
+		}
+
+		var oldMappings []v1.TestOwnership
+		if diffFlags.oldFromBigQuery {
+			tableManager, err := diffFlags.bigqueryFlags.NewMappingStore(context.Background())
+			if err != nil {
+				log.WithError(err).Fatal("could not obtain bigquery client")
+			}
+			oldMappings, err = tableManager.ListLatestMappings()
+			if err != nil {
+				log.WithError(err).Fatal("could not list mappings from bigquery")
+			}
+		} else {
+			var err error
+			oldMappings, err = mapping.LoadFile(diffFlags.oldFile)
+			if err != nil {
+				log.WithError(err).Fatal("could not read old mappings")
+			}
+		}
+
+		newMappings, err := mapping.LoadFile(diffFlags.newFile)
+		if err != nil {
+			log.WithError(err).Fatal("could not read new mappings")
+		}
+
+		diff := mapping.Compare(oldMappings, newMappings)
+		if err := mapping.WriteReport(os.Stdout, diff, diffFlags.format); err != nil {
+			log.WithError(err).Fatal("could not write report")
+		}
+	},
+}
+
+type DiffFlags struct {
+	oldFile         string
+	newFile         string
+	oldFromBigQuery bool
+	format          string
+	bigqueryFlags   *flags.Flags
+}
+
+var diffFlags = NewDiffFlags()
+
+func NewDiffFlags() *DiffFlags {
+	return &DiffFlags{
+		bigqueryFlags: flags.NewFlags(),
+	}
+}
+
+func (f *DiffFlags) BindFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.oldFile, "old", "", "File containing the previous mappings")
+	fs.StringVar(&f.newFile, "new", "mapping.json", "File containing the new mappings")
+	fs.BoolVar(&f.oldFromBigQuery, "old-from-bigquery", false, "Use the latest snapshot in BigQuery as the previous mappings")
+	fs.StringVar(&f.format, "format", mapping.FormatText, "Output format (one of: text, json, markdown)")
+	f.bigqueryFlags.BindFlags(fs)
+}
+
+func init() {
+	diffFlags.BindFlags(diffCmd.Flags())
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery/filestore"
+	"github.com/openshift-eng/ci-test-mapping/pkg/mapping"
+	"github.com/openshift-eng/ci-test-mapping/pkg/version"
+)
+
+// TestBigQueryModeOffline runs the bigquery code paths end to end against the
+// file-backed stand-in for BigQuery.
+func TestBigQueryModeOffline(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// map writes bigquery_tests.json to the working directory
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd) //nolint:errcheck
+
+	// Test binaries carry no VCS information, and don't carry over ownership
+	// without a commit
+	version.Commit = "abc123"
+	defer func() { version.Commit = "" }()
+
+	datasetDir := filepath.Join(dir, "dataset")
+	dataset, err := filestore.NewDataset(datasetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	junit := filestore.NewTestTableManager(dataset, bigquery.DefaultTestTableName, nil)
+	if err := junit.InsertRows([]filestore.JUnitRow{
+		{TestName: "[sig-storage] a storage test", TestSuite: "openshift-tests"},
+		{TestName: "[sig-something] an unknown test", TestSuite: "openshift-tests"},
+		{TestName: "Build image foo from the repository", TestSuite: "openshift-tests"},
+		{TestName: "[sig-storage] not in an allowed suite", TestSuite: "some-other-suite"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	mappingFile := filepath.Join(dir, "mapping.json")
+	run := func(args ...string) {
+		t.Helper()
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("%v failed: %+v", args, err)
+		}
+	}
+
+	// Map and push twice, so there's an older snapshot to prune
+	for i := 0; i < 2; i++ {
+		run("map", "--mode", "bigquery", "--bigquery-local-dir", datasetDir,
+			"--mapping-file", mappingFile, "--push-to-bigquery")
+	}
+
+	mappings, err := mapping.LoadFile(mappingFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	components := make(map[string]string)
+	for _, m := range mappings {
+		components[m.Name] = m.Component
+	}
+	wantComponents := map[string]string{
+		"[sig-storage] a storage test":    "Storage",
+		"[sig-something] an unknown test": "Unknown",
+	}
+	if len(components) != len(wantComponents) {
+		t.Errorf("map produced %v, want %v", components, wantComponents)
+	}
+	for name, want := range wantComponents {
+		if components[name] != want {
+			t.Errorf("map assigned %q to %q, want %q", name, components[name], want)
+		}
+	}
+
+	store := filestore.NewMappingTableManager(dataset, bigquery.DefaultMappingTableName)
+	stored, err := store.ListMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2*len(wantComponents) {
+		t.Errorf("got %d rows in the mapping table, want %d", len(stored), 2*len(wantComponents))
+	}
+	// The second run carries over the first run's records, with their
+	// fingerprints
+	for _, m := range stored {
+		if m.TestFingerprint == "" || m.RegistryFingerprint == "" {
+			t.Errorf("record for %q is missing its fingerprints", m.Name)
+		}
+	}
+
+	// Pushing a run again is a no-op
+	run("map", "--mode", "bigquery", "--bigquery-local-dir", datasetDir,
+		"--mapping-file", mappingFile, "--push-to-bigquery", "--bigquery-push-mode", "load",
+		"--run-id", mapping.Latest(stored)[0].RunID)
+	repushed, err := store.ListMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repushed) != len(stored) {
+		t.Errorf("got %d rows in the mapping table after pushing a run again, want %d", len(repushed), len(stored))
+	}
+
+	// Each run is recorded once in the run history, with the provenance its
+	// records carry
+	runs, err := store.ListRuns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("got %d runs in the run history, want 2", len(runs))
+	}
+	latestMappings, err := store.ListLatestMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := latestMappings[0]
+	if runs[0].RunID != latest.RunID || runs[0].TestSource != "bigquery" || runs[0].InputTestCount != len(wantComponents) {
+		t.Errorf("run history has latest run %+v, want run %q from bigquery with %d tests", runs[0], latest.RunID, len(wantComponents))
+	}
+	if latest.TestSource != runs[0].TestSource || latest.ToolCommit != runs[0].ToolCommit || latest.InputTestCount != runs[0].InputTestCount {
+		t.Errorf("record %+v doesn't carry the provenance of run %+v", latest, runs[0])
+	}
+	if runs[0].Carried != len(wantComponents) || runs[0].Records != len(wantComponents) {
+		t.Errorf("latest run carried %d of %d records, want all %d", runs[0].Carried, runs[0].Records, len(wantComponents))
+	}
+
+	run("prune", "--bigquery-local-dir", datasetDir, "--dry-run")
+	if dryRun, err := store.ListMappings(); err != nil || len(dryRun) != len(stored) {
+		t.Errorf("got %d rows in the mapping table after a dry run, %v, want %d", len(dryRun), err, len(stored))
+	}
+
+	run("prune", "--bigquery-local-dir", datasetDir, "--dry-run=false")
+	stored, err = store.ListMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != len(wantComponents) || len(mapping.Latest(stored)) != len(stored) {
+		t.Errorf("got %d rows in the mapping table after pruning, want only the latest %d", len(stored), len(wantComponents))
+	}
+
+	// A dirty build's commit doesn't change with its code, so it identifies
+	// every test, even after a run from the same build
+	version.Commit = "abc123" + version.DirtySuffix
+	for _, runID := range []string{"dirty-1", "dirty-2"} {
+		run("map", "--mode", "bigquery", "--bigquery-local-dir", datasetDir,
+			"--mapping-file", mappingFile, "--push-to-bigquery", "--run-id", runID)
+	}
+	runs, err = store.ListRuns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs[0].Carried != 0 || runs[0].Records != len(wantComponents) {
+		t.Errorf("run from a dirty build carried %d of %d records, want none", runs[0].Carried, runs[0].Records)
+	}
+}
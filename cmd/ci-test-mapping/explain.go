@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/components"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Explain how a single test is mapped to a component",
+	Run: func(cmd *cobra.Command, args []string) {
+		if explainFlags.name == "" {
+			cmd.Usage() //nolint:errcheck
+			log.Fatal("please supply the test name with --name")
+		}
+
+		reg, err := newComponentRegistry()
+		if err != nil {
+			log.WithError(err).Fatal("could not load component registry")
+		}
+
+		explanation, err := components.Explain(reg, &v1.TestInfo{
+			Name:  explainFlags.name,
+			Suite: explainFlags.suite,
+		})
+		if err != nil {
+			log.WithError(err).Fatal("could not explain test mapping")
+		}
+
+		if err := components.WriteExplanation(os.Stdout, explanation, explainFlags.format, explainFlags.all); err != nil {
+			log.WithError(err).Fatal("could not write explanation")
+		}
+	},
+}
+
+type ExplainFlags struct {
+	name   string
+	suite  string
+	format string
+	all    bool
+}
+
+var explainFlags = NewExplainFlags()
+
+func NewExplainFlags() *ExplainFlags {
+	return &ExplainFlags{}
+}
+
+func (f *ExplainFlags) BindFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.name, "name", "", "Name of the test to explain")
+	fs.StringVar(&f.suite, "suite", "", "Suite of the test to explain")
+	fs.StringVar(&f.format, "format", components.ExplainFormatText, "Output format (one of: text, json)")
+	fs.BoolVar(&f.all, "all", false, "List the components that did not claim the test in text output")
+}
+
+func init() {
+	explainFlags.BindFlags(explainCmd.Flags())
+	rootCmd.AddCommand(explainCmd)
+}
@@ -1,23 +1,118 @@
 package flags
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/pflag"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery/filestore"
 )
 
-// Flags contain auth information for Google BigQuery services.
+// Flags contain auth information for Google BigQuery services, and the
+// location of the tables we read from and write to. When LocalDir is set, a
+// file-backed stand-in for the dataset is used instead of BigQuery.
 type Flags struct {
 	ServiceAccountCredentialFile string
 	OAuthClientCredentialFile    string
+
+	Project      string
+	Dataset      string
+	TestTable    string
+	MappingTable string
+	PushMode     string
+
+	LocalDir string
+
+	client       *bigquery.Client
+	localDataset *filestore.Dataset
 }
 
 func NewFlags() *Flags {
 	return &Flags{
 		OAuthClientCredentialFile: os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"),
+		Project:                   bigquery.DefaultProjectName,
+		Dataset:                   bigquery.DefaultDatasetName,
+		TestTable:                 bigquery.DefaultTestTableName,
+		MappingTable:              bigquery.DefaultMappingTableName,
+		PushMode:                  bigquery.PushModeStream,
 	}
 }
 
+// HasBackend reports whether credentials or a local dataset were supplied.
+func (f *Flags) HasBackend() bool {
+	return f.ServiceAccountCredentialFile != "" || f.OAuthClientCredentialFile != "" || f.LocalDir != ""
+}
+
+// NewClient returns a BigQuery client for the configured project and dataset.
+// The client is created once and reused.
+func (f *Flags) NewClient(ctx context.Context) (*bigquery.Client, error) {
+	if f.client != nil {
+		return f.client, nil
+	}
+
+	client, err := bigquery.NewClient(ctx, f.Project, f.Dataset, f.ServiceAccountCredentialFile, f.OAuthClientCredentialFile)
+	if err != nil {
+		return nil, err
+	}
+	f.client = client
+	return client, nil
+}
+
+func (f *Flags) newLocalDataset() (*filestore.Dataset, error) {
+	if f.localDataset != nil {
+		return f.localDataset, nil
+	}
+
+	dataset, err := filestore.NewDataset(f.LocalDir)
+	if err != nil {
+		return nil, err
+	}
+	f.localDataset = dataset
+	return dataset, nil
+}
+
+// NewMappingStore returns the store for the mapping table.
+func (f *Flags) NewMappingStore(ctx context.Context) (bigquery.MappingStore, error) {
+	if !contains(bigquery.PushModes, f.PushMode) {
+		return nil, fmt.Errorf("invalid push mode %q, must be one of: %s", f.PushMode, strings.Join(bigquery.PushModes, ", "))
+	}
+
+	if f.LocalDir != "" {
+		dataset, err := f.newLocalDataset()
+		if err != nil {
+			return nil, err
+		}
+		return filestore.NewMappingTableManager(dataset, f.MappingTable), nil
+	}
+
+	client, err := f.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return bigquery.NewMappingTableManager(ctx, client, f.MappingTable, f.PushMode), nil
+}
+
+// NewTestLister returns a lister for the tests in the junit table.
+func (f *Flags) NewTestLister(ctx context.Context, config *bigquery.TestTableConfig) (bigquery.TestLister, error) {
+	if f.LocalDir != "" {
+		dataset, err := f.newLocalDataset()
+		if err != nil {
+			return nil, err
+		}
+		return filestore.NewTestTableManager(dataset, f.TestTable, config), nil
+	}
+
+	client, err := f.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return bigquery.NewTestTableManager(ctx, client, f.TestTable, config), nil
+}
+
 func (f *Flags) BindFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&f.ServiceAccountCredentialFile,
 		"google-service-account-credential-file",
@@ -28,4 +123,44 @@ func (f *Flags) BindFlags(fs *pflag.FlagSet) {
 		"google-oauth-credential-file",
 		f.OAuthClientCredentialFile,
 		"location of a credential file described by https://developers.google.com/people/quickstart/go, setup from https://cloud.google.com/bigquery/docs/authentication/end-user-installed#client-credentials")
+
+	fs.StringVar(&f.Project,
+		"bigquery-project",
+		f.Project,
+		"BigQuery project")
+
+	fs.StringVar(&f.Dataset,
+		"bigquery-dataset",
+		f.Dataset,
+		"BigQuery dataset containing the test and mapping tables")
+
+	fs.StringVar(&f.TestTable,
+		"bigquery-test-table",
+		f.TestTable,
+		"BigQuery table of junit results to read tests from")
+
+	fs.StringVar(&f.MappingTable,
+		"bigquery-mapping-table",
+		f.MappingTable,
+		"BigQuery table to write mappings to")
+
+	fs.StringVar(&f.PushMode,
+		"bigquery-push-mode",
+		f.PushMode,
+		"How to push mappings: stream uses the streaming inserter; load appends the snapshot atomically with a load job; "+
+			"merge stages the snapshot and MERGEs in the rows that are missing")
+
+	fs.StringVar(&f.LocalDir,
+		"bigquery-local-dir",
+		f.LocalDir,
+		"Use a file-backed stand-in for the BigQuery dataset stored in this directory, instead of BigQuery. For offline testing.")
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,62 @@
+package flags
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
+)
+
+// TestTableFlags control which tests are listed from the BigQuery junit table.
+type TestTableFlags struct {
+	ConfigFile         string
+	Suites             []string
+	IgnoredTests       []string
+	IgnoredTestRegexes []string
+}
+
+func NewTestTableFlags() *TestTableFlags {
+	return &TestTableFlags{}
+}
+
+func (f *TestTableFlags) BindFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.ConfigFile,
+		"test-table-config",
+		f.ConfigFile,
+		"YAML or JSON file with the suites to include and the test name patterns to ignore; defaults to the built-in lists")
+
+	fs.StringArrayVar(&f.Suites,
+		"suite",
+		f.Suites,
+		"Suite to include, may be repeated; replaces the suites from the config")
+
+	fs.StringArrayVar(&f.IgnoredTests,
+		"ignore-test",
+		f.IgnoredTests,
+		"SQL LIKE pattern of test names to exclude, may be repeated; added to the config")
+
+	fs.StringArrayVar(&f.IgnoredTestRegexes,
+		"ignore-test-regex",
+		f.IgnoredTestRegexes,
+		"Regular expression of test names to exclude, may be repeated; added to the config")
+}
+
+// Config returns the test table configuration from the config file, or the
+// defaults, with the flag overrides applied.
+func (f *TestTableFlags) Config() (*bigquery.TestTableConfig, error) {
+	config := bigquery.DefaultTestTableConfig()
+	if f.ConfigFile != "" {
+		var err error
+		config, err = bigquery.LoadTestTableConfig(f.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(f.Suites) > 0 {
+		config.Suites = f.Suites
+	}
+	config.IgnoredTests = append(config.IgnoredTests, f.IgnoredTests...)
+	config.IgnoredTestRegexes = append(config.IgnoredTestRegexes, f.IgnoredTestRegexes...)
+
+	return config, config.Validate()
+}
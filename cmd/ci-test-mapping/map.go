@@ -2,9 +2,14 @@ package cmd
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"runtime"
 	"sort"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/civil"
@@ -16,7 +21,9 @@ import (
 	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
 	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
 	"github.com/openshift-eng/ci-test-mapping/pkg/components"
-	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
+	"github.com/openshift-eng/ci-test-mapping/pkg/mapping"
+	"github.com/openshift-eng/ci-test-mapping/pkg/sources"
+	"github.com/openshift-eng/ci-test-mapping/pkg/version"
 )
 
 const ModeBigQuery = "bigquery"
@@ -26,48 +33,85 @@ var mapCmd = &cobra.Command{
 	Use:   "map",
 	Short: "Map tests to components and capabilities",
 	Run: func(cmd *cobra.Command, args []string) {
+		start := time.Now()
 		verifyParams(cmd)
 
 		var tests []v1.TestInfo
-		var tableManager *bigquery.MappingTableManager
+		var previousMappings []v1.TestOwnership
+		var tableManager bigquery.MappingStore
 
 		if f.mode == ModeBigQuery {
-			// Get a bigquery client
-			bigqueryClient, err := bigquery.NewClient(context.Background(),
-				f.bigqueryFlags.ServiceAccountCredentialFile,
-				f.bigqueryFlags.OAuthClientCredentialFile)
+			// Get the mapping table from bigquery
+			var err error
+			tableManager, err = f.bigqueryFlags.NewMappingStore(context.Background())
 			if err != nil {
 				log.WithError(err).Fatal("could not obtain bigquery client")
 			}
 
-			// Create or update schema for mapping table
-			tableManager = bigquery.NewMappingTableManager(context.Background(), bigqueryClient)
-			if err := tableManager.Migrate(); err != nil {
+			// Create the mapping table, or apply pending migrations to it
+			if err := tableManager.Migrate(f.allowDestructive); err != nil {
 				log.WithError(err).Fatal("could not migrate mapping table")
 			}
+		}
 
-			// Get a list of all tests from bigquery - this could be swapped out with other
-			// mechanisms to get test details later on.
-			testLister := bigquery.NewTestTableManager(context.Background(), bigqueryClient)
-			tests, err = testLister.ListTests()
-			if err != nil {
-				log.WithError(err).Fatal("could not list tests")
-			}
+		// Get a list of all tests from the configured sources
+		testSource, err := newTestSource()
+		if err != nil {
+			cmd.Usage() //nolint:errcheck
+			log.WithError(err).Fatal("invalid test source")
+		}
+		tests, err = testSource.ListTests()
+		if err != nil {
+			log.WithError(err).Fatal("could not list tests")
+		}
+		log.WithField("source", testSource.Name()).Infof("listed %d tests", len(tests))
+
+		if f.mode == ModeBigQuery {
 			if err := writeRecords(tests, "bigquery_tests.json"); err != nil {
 				log.WithError(err).Fatal("couldn't write records")
 			}
-		} else {
-			data, err := os.ReadFile(f.testsFile)
-			if err != nil {
-				log.WithError(err).Fatalf("could not fetch tests from file")
+
+			if !f.skipObsoleteCheck || !f.full {
+				previousMappings, err = tableManager.ListLatestMappings()
+				if err != nil {
+					log.WithError(err).Fatal("could not list previous mappings")
+				}
 			}
-			if err := json.Unmarshal(data, &tests); err != nil {
-				log.WithError(err).Fatalf("could not marshal tests from file")
+		} else if !f.skipObsoleteCheck || !f.full {
+			previousMappings, err = mapping.LoadFile(f.mappingFile)
+			if err != nil && !os.IsNotExist(err) {
+				log.WithError(err).Fatal("could not read previous mappings")
 			}
 		}
 
 		// Create a registry of components
-		componentRegistry := registry.NewComponentRegistry()
+		componentRegistry, err := newComponentRegistry()
+		if err != nil {
+			log.WithError(err).Fatal("could not load component registry")
+		}
+		registryFingerprint, err := componentRegistry.Fingerprint()
+		if err != nil {
+			log.WithError(err).Fatal("could not fingerprint component registry")
+		}
+
+		// Carry over the ownership of tests that are unchanged since the
+		// previous run, unless the registry changed. The fingerprint only
+		// covers the matching code through the build's commit, so a build
+		// whose commit doesn't identify its code identifies every test.
+		toIdentify := tests
+		var carried []v1.TestOwnership
+		full := f.full
+		if _, commit := version.Get(); !full && !version.Identifies(commit) {
+			log.WithField("commit", commit).Warning("the build's commit doesn't identify its code, identifying every test instead of carrying over ownership")
+			full = true
+		}
+		if !full {
+			carried, toIdentify = mapping.CarryOver(previousMappings, tests, registryFingerprint)
+			log.WithFields(log.Fields{
+				"carried":    len(carried),
+				"identified": len(toIdentify),
+			}).Infof("carrying over unchanged ownership from the previous run")
+		}
 
 		// Query each component for each test
 		now := time.Now()
@@ -75,25 +119,88 @@ var mapCmd = &cobra.Command{
 		log.Infof("mapping tests to ownership")
 		var newMappings []v1.TestOwnership
 		var matched, unmatched int
-		for i := range tests {
-			ownership, err := components.IdentifyTest(componentRegistry, &tests[i])
-			if err != nil {
-				log.WithError(err).Fatalf("encountered error in component identification")
+		var conflicts []*components.ConflictError
+		undeclared := make(map[undeclaredCapability]int)
+		results, err := components.IdentifyTests(componentRegistry, toIdentify, f.workers)
+		if err != nil {
+			fields := log.Fields{}
+			var testErr *components.TestError
+			if errors.As(err, &testErr) {
+				fields["name"] = testErr.Name
+				fields["suite"] = testErr.Suite
 			}
-			if ownership != nil {
+			log.WithError(err).WithFields(fields).Fatalf("encountered error in component identification")
+		}
+		for i := range results {
+			if ownership := results[i].Ownership; ownership != nil {
+				ownership.TestFingerprint = mapping.FingerprintTest(&toIdentify[i])
+			}
+		}
+		for i := range carried {
+			results = append(results, components.Result{Ownership: &carried[i]})
+		}
+		for _, result := range results {
+			if result.Conflict != nil {
+				conflicts = append(conflicts, result.Conflict)
+				continue
+			}
+			if ownership := result.Ownership; ownership != nil {
 				if ownership.Component == components.DefaultComponent {
 					unmatched++
 				} else {
 					matched++
 				}
+				for _, capability := range componentRegistry.UndeclaredCapabilities(ownership.Component, ownership.Capabilities, components.DefaultCapability) {
+					undeclared[undeclaredCapability{component: ownership.Component, capability: capability}]++
+				}
 				ownership.CreatedAt = createdAt
+				ownership.RegistryFingerprint = registryFingerprint
 				newMappings = append(newMappings, *ownership)
 			}
 		}
 
+		if len(conflicts) > 0 {
+			for _, conflict := range conflicts {
+				log.WithFields(log.Fields{
+					"name":  conflict.Name,
+					"suite": conflict.Suite,
+				}).Error(conflict.Error())
+			}
+			log.Fatalf("%d tests have conflicting owners, please resolve them using the priority field", len(conflicts))
+		}
+
+		checkUndeclaredCapabilities(undeclared)
+
+		var obsolete []v1.TestOwnership
+		if !f.skipObsoleteCheck && len(previousMappings) > 0 {
+			obsolete = checkObsolete(previousMappings, newMappings, createdAt)
+			newMappings = append(newMappings, obsolete...)
+		}
+
+		// Tag the snapshot with the run ID, so pushing it again is a no-op,
+		// its size, so prune can tell whether it's complete, and the code and
+		// tests that produced it
+		runID := f.runID
+		if runID == "" {
+			runID = newRunID(now)
+		}
+		toolVersion, toolCommit := version.Get()
+		for i := range newMappings {
+			newMappings[i].RunID = runID
+			newMappings[i].SnapshotSize = len(newMappings)
+			newMappings[i].ToolVersion = toolVersion
+			newMappings[i].ToolCommit = toolCommit
+			newMappings[i].TestSource = testSource.Name()
+			newMappings[i].InputTestCount = len(tests)
+		}
+		log.WithField("run", runID).Infof("tagged %d records with the run ID", len(newMappings))
+
 		// Ensure slice is sorted
-		sort.Slice(newMappings, func(i, j int) bool {
-			return newMappings[i].Name < newMappings[j].Name && newMappings[i].Suite < newMappings[j].Suite
+		sort.SliceStable(newMappings, func(i, j int) bool {
+			if newMappings[i].Name != newMappings[j].Name {
+				return newMappings[i].Name < newMappings[j].Name
+			}
+			return newMappings[i].Suite < newMappings[j].Suite
 		})
 
 		log.WithFields(log.Fields{
@@ -101,12 +208,33 @@ var mapCmd = &cobra.Command{
 			"unmatched": unmatched,
 		}).Infof("mapping tests to ownership complete in %v", time.Since(now))
 
+		run := v1.MappingRun{
+			RunID:               runID,
+			CreatedAt:           createdAt,
+			ToolVersion:         toolVersion,
+			ToolCommit:          toolCommit,
+			RegistryFingerprint: registryFingerprint,
+			TestSource:          testSource.Name(),
+			InputTestCount:      len(tests),
+			Records:             len(newMappings),
+			Matched:             matched,
+			Unmatched:           unmatched,
+			Carried:             len(carried),
+			Identified:          len(toIdentify),
+			Obsolete:            len(obsolete),
+			DurationSeconds:     time.Since(start).Seconds(),
+			ComponentCounts:     componentCounts(newMappings),
+		}
+
 		if f.mode == ModeBigQuery && f.pushToBQ {
 			now = time.Now()
 			log.Infof("pushing to bigquery...")
 			if err := tableManager.PushMappings(newMappings); err != nil {
 				log.WithError(err).Fatalf("could not push records to bigquery")
 			}
+			if err := tableManager.PushRun(run); err != nil {
+				log.WithError(err).Fatalf("could not record run in bigquery")
+			}
 			log.Infof("push finished in %+v", time.Since(now))
 		}
 
@@ -117,23 +245,34 @@ var mapCmd = &cobra.Command{
 }
 
 type MapFlags struct {
-	mode          string
-	mappingFile   string
-	testsFile     string
-	pushToBQ      bool
-	bigqueryFlags *flags.Flags
+	mode                   string
+	mappingFile            string
+	testsFile              string
+	pushToBQ               bool
+	obsoleteApprovalsFile  string
+	testSources            []string
+	skipObsoleteCheck      bool
+	full                   bool
+	allowDestructive       bool
+	runID                  string
+	undeclaredCapabilities string
+	workers                int
+	bigqueryFlags          *flags.Flags
+	testTableFlags         *flags.TestTableFlags
 }
 
 var f = NewMapFlags()
 
 func NewMapFlags() *MapFlags {
 	return &MapFlags{
-		bigqueryFlags: flags.NewFlags(),
+		bigqueryFlags:  flags.NewFlags(),
+		testTableFlags: flags.NewTestTableFlags(),
 	}
 }
 
 func (f *MapFlags) BindFlags(fs *pflag.FlagSet) {
 	f.bigqueryFlags.BindFlags(fs)
+	f.testTableFlags.BindFlags(fs)
 }
 
 func init() {
@@ -142,6 +281,23 @@ func init() {
 	mapCmd.PersistentFlags().StringVar(&f.testsFile, "tests-file", "bigquery_tests.json", "File containing a list of tests to process, see bigquery_tests.json. For local testing without access to canonical test data from BigQuery.")
 	mapCmd.PersistentFlags().StringVar(&f.mode, "mode", "local", "Mode (one of: local, bigquery). Local mode doesn't require access to BigQuery and is suitable for local development.")
 	mapCmd.PersistentFlags().BoolVar(&f.pushToBQ, "push-to-bigquery", false, "whether or not to push the updated records to bigquery")
+	mapCmd.PersistentFlags().StringArrayVar(&f.testSources, "test-source", nil,
+		"Source of tests to map, may be repeated: bigquery, json:<file>, junit:<directory>, or openshift-tests:<file>. "+
+			"Tests from several sources are merged and deduplicated. Defaults to bigquery in bigquery mode, and json:<tests-file> in local mode.")
+	mapCmd.PersistentFlags().StringVar(&f.obsoleteApprovalsFile, "obsolete-approvals-file", "obsolete_approvals.yaml",
+		"File listing previously mapped tests that are approved to lose their ownership")
+	mapCmd.PersistentFlags().BoolVar(&f.skipObsoleteCheck, "skip-obsolete-check", false,
+		"Don't fail when previously mapped tests disappear or change their stable ID")
+	mapCmd.PersistentFlags().BoolVar(&f.full, "full", false,
+		"Identify every test, instead of carrying over the ownership of tests unchanged since the previous run")
+	mapCmd.PersistentFlags().BoolVar(&f.allowDestructive, "allow-destructive-migrations", false,
+		"Apply mapping table migrations that rename or drop columns")
+	mapCmd.PersistentFlags().StringVar(&f.runID, "run-id", "",
+		"ID to tag the mapping snapshot with, such as a CI job ID; pushing a run ID that's already in the table is a no-op. Generated if unset.")
+	mapCmd.PersistentFlags().StringVar(&f.undeclaredCapabilities, "undeclared-capabilities", undeclaredCapabilitiesWarn,
+		"What to do when a test is assigned a capability missing from its component's capability catalog (one of: warn, error)")
+	mapCmd.PersistentFlags().IntVar(&f.workers, "workers", runtime.NumCPU(),
+		"Number of tests to map concurrently")
 	f.BindFlags(mapCmd.Flags())
 	rootCmd.AddCommand(mapCmd)
 }
@@ -149,7 +305,7 @@ func init() {
 func verifyParams(cmd *cobra.Command) {
 	switch f.mode {
 	case ModeBigQuery:
-		if f.bigqueryFlags.ServiceAccountCredentialFile == "" && f.bigqueryFlags.OAuthClientCredentialFile == "" {
+		if !f.bigqueryFlags.HasBackend() {
 			cmd.Usage()                                                           //nolint:errcheck
 			log.Fatalf("please supply bigquery credentials, or use --mode=local") //nolint
 		}
@@ -159,7 +315,7 @@ func verifyParams(cmd *cobra.Command) {
 			log.Fatalf("cannot push to bigquery in --mode=local") //nolint
 		}
 
-		if f.bigqueryFlags.ServiceAccountCredentialFile != "" || f.bigqueryFlags.OAuthClientCredentialFile != "" {
+		if f.bigqueryFlags.HasBackend() {
 			cmd.Usage()                                                                                              //nolint:errcheck
 			log.Fatalf("bigquery credentials not required for local mode, did you mean to specify --mode=bigquery?") //nolint
 		}
@@ -167,6 +323,172 @@ func verifyParams(cmd *cobra.Command) {
 		cmd.Usage()                                                                  //nolint:errcheck
 		log.Fatalf("invalid mode, must be one of: bigquery, local. got: %q", f.mode) //nolint
 	}
+
+	if f.undeclaredCapabilities != undeclaredCapabilitiesWarn && f.undeclaredCapabilities != undeclaredCapabilitiesError {
+		cmd.Usage() //nolint:errcheck
+		log.Fatalf("invalid --undeclared-capabilities, must be one of: %s, %s. got: %q",
+			undeclaredCapabilitiesWarn, undeclaredCapabilitiesError, f.undeclaredCapabilities) //nolint
+	}
+}
+
+// newTestSource builds the test source described by --test-source.
+func newTestSource() (sources.TestSource, error) {
+	specs := f.testSources
+	if len(specs) == 0 {
+		if f.mode == ModeBigQuery {
+			specs = []string{"bigquery"}
+		} else {
+			specs = []string{"json:" + f.testsFile}
+		}
+	}
+
+	merged := sources.NewMerged()
+	for _, spec := range specs {
+		kind, arg, _ := strings.Cut(spec, ":")
+		if kind != "bigquery" && arg == "" {
+			return nil, fmt.Errorf("test source %q requires a path, e.g. %s:<path>", spec, kind)
+		}
+
+		switch kind {
+		case "bigquery":
+			if f.mode != ModeBigQuery {
+				return nil, fmt.Errorf("test source %q requires --mode=bigquery", spec)
+			}
+			testTableConfig, err := f.testTableFlags.Config()
+			if err != nil {
+				return nil, err
+			}
+			testLister, err := f.bigqueryFlags.NewTestLister(context.Background(), testTableConfig)
+			if err != nil {
+				return nil, err
+			}
+			merged.Sources = append(merged.Sources, &sources.BigQuery{TestLister: testLister})
+		case "json":
+			merged.Sources = append(merged.Sources, &sources.JSONFile{Path: arg})
+		case "junit":
+			merged.Sources = append(merged.Sources, &sources.JUnitDirectory{Dir: arg})
+		case "openshift-tests":
+			merged.Sources = append(merged.Sources, &sources.OpenShiftTestsList{Path: arg})
+		default:
+			return nil, fmt.Errorf("unknown test source %q", spec)
+		}
+	}
+
+	return merged, nil
+}
+
+const (
+	undeclaredCapabilitiesWarn  = "warn"
+	undeclaredCapabilitiesError = "error"
+)
+
+type undeclaredCapability struct {
+	component  string
+	capability string
+}
+
+// checkUndeclaredCapabilities reports capabilities that were assigned to
+// tests but are missing from their component's catalog, along with the
+// number of tests they were assigned to, and fails if configured to.
+func checkUndeclaredCapabilities(undeclared map[undeclaredCapability]int) {
+	if len(undeclared) == 0 {
+		return
+	}
+
+	keys := make([]undeclaredCapability, 0, len(undeclared))
+	for k := range undeclared {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].component != keys[j].component {
+			return keys[i].component < keys[j].component
+		}
+		return keys[i].capability < keys[j].capability
+	})
+
+	for _, k := range keys {
+		entry := log.WithFields(log.Fields{
+			"component":  k.component,
+			"capability": k.capability,
+			"tests":      undeclared[k],
+		})
+		if f.undeclaredCapabilities == undeclaredCapabilitiesError {
+			entry.Error("capability is not declared in the component's capability catalog")
+		} else {
+			entry.Warning("capability is not declared in the component's capability catalog")
+		}
+	}
+
+	if f.undeclaredCapabilities == undeclaredCapabilitiesError {
+		log.Fatalf("%d capabilities are not declared in their component's capability catalog", len(keys))
+	}
+}
+
+// checkObsolete compares the new mappings with the previous ones, and fails
+// if any previously mapped test lost its ownership without an approval. It
+// returns the approved obsolete records, which are emitted along with the new
+// mappings.
+func checkObsolete(previousMappings, newMappings []v1.TestOwnership, createdAt civil.DateTime) []v1.TestOwnership {
+	approvals, err := mapping.LoadObsoleteApprovals(f.obsoleteApprovalsFile)
+	if err != nil {
+		log.WithError(err).Fatal("could not load obsolete approvals")
+	}
+
+	check := mapping.CheckObsolete(previousMappings, newMappings, approvals)
+	for _, approval := range check.Unused {
+		log.WithFields(log.Fields{
+			"id":   approval.ID,
+			"name": approval.Name,
+		}).Warningf("obsolete approval in %s doesn't match any test that lost its ownership", f.obsoleteApprovalsFile)
+	}
+	if len(check.Unapproved) > 0 {
+		for i := range check.Unapproved {
+			log.WithFields(log.Fields{
+				"id":        check.Unapproved[i].ID,
+				"name":      check.Unapproved[i].Name,
+				"suite":     check.Unapproved[i].Suite,
+				"component": check.Unapproved[i].Component,
+			}).Error("previously mapped test disappeared or changed its stable ID")
+		}
+		log.Fatalf("%d previously mapped tests lost their ownership; if this is intended, add them to %s with an approver and reason",
+			len(check.Unapproved), f.obsoleteApprovalsFile)
+	}
+
+	for i := range check.Approved {
+		check.Approved[i].CreatedAt = createdAt
+	}
+	log.Infof("%d previously mapped tests are approved as obsolete", len(check.Approved))
+	return check.Approved
+}
+
+// newRunID returns a unique ID for a mapping run, starting with its time so
+// run IDs sort chronologically.
+func newRunID(now time.Time) string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		log.WithError(err).Fatal("could not generate run ID")
+	}
+	return fmt.Sprintf("%s-%x", now.UTC().Format("20060102T150405Z"), suffix)
+}
+
+// componentCounts counts the tests owned by each component, excluding
+// obsolete records.
+func componentCounts(mappings []v1.TestOwnership) []v1.ComponentCount {
+	counts := make(map[string]int)
+	for i := range mappings {
+		if !mappings[i].StaffApprovedObsolete {
+			counts[mappings[i].Component]++
+		}
+	}
+
+	result := make([]v1.ComponentCount, 0, len(counts))
+	for component, tests := range counts {
+		result = append(result, v1.ComponentCount{Component: component, Tests: tests})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Component < result[j].Component
+	})
+	return result
 }
 
 func writeRecords(records interface{}, filename string) error {
@@ -2,6 +2,11 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -15,24 +20,59 @@ var pruneCommand = &cobra.Command{
 	Use:   "prune",
 	Short: "Prune older mapping records from the database",
 	Run: func(cmd *cobra.Command, args []string) {
-		// Get a bigquery client
-		bigqueryClient, err := bigquery.NewClient(context.Background(),
-			pruneFlags.bigqueryFlags.ServiceAccountCredentialFile,
-			pruneFlags.bigqueryFlags.OAuthClientCredentialFile)
+		// Get the mapping table
+		tableManager, err := pruneFlags.bigqueryFlags.NewMappingStore(context.Background())
 		if err != nil {
 			log.WithError(err).Fatal("could not obtain bigquery client")
-			cmd.Usage() //nolint
 		}
 
-		// Create or update schema for mapping table
-		tableManager := bigquery.NewMappingTableManager(context.Background(), bigqueryClient)
-		if err := tableManager.PruneMappings(); err != nil {
-			log.WithError(err).Fatal("could not prune mapping table")
+		plan, err := tableManager.PruneMappings(bigquery.RetentionPolicy{
+			KeepLast:     pruneFlags.keepLast,
+			KeepWithin:   pruneFlags.keepWithin,
+			MinSizeRatio: pruneFlags.minSizeRatio,
+			Force:        pruneFlags.force,
+		}, pruneFlags.dryRun)
+		if plan != nil {
+			if err := writePrunePlan(os.Stdout, plan, pruneFlags.dryRun); err != nil {
+				log.WithError(err).Fatal("could not write prune plan")
+			}
+		}
+		if err != nil {
+			log.WithError(err).Fatal("could not prune mapping table; use --force to prune anyway")
 		}
 	},
 }
 
+// writePrunePlan lists every snapshot with its row count, and whether it's
+// kept or pruned.
+func writePrunePlan(out io.Writer, plan *bigquery.PrunePlan, dryRun bool) error {
+	pruned := "pruned"
+	if dryRun {
+		pruned = "would be pruned"
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CREATED AT\tRUN ID\tROWS\tACTION")
+	for _, s := range plan.Keep {
+		fmt.Fprintf(w, "%s\t%s\t%d\tkept\n", s.CreatedAt, s.RunID, s.Rows)
+	}
+	for _, s := range plan.Prune {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", s.CreatedAt, s.RunID, s.Rows, pruned)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "%d snapshots with %d rows %s\n", len(plan.Prune), plan.PrunedRows(), pruned)
+	return err
+}
+
 type PruneFlags struct {
+	keepLast      int
+	keepWithin    time.Duration
+	dryRun        bool
+	minSizeRatio  float64
+	force         bool
 	bigqueryFlags *flags.Flags
 }
 
@@ -45,7 +85,13 @@ func NewPruneFlags() *PruneFlags {
 }
 
 func (f *PruneFlags) BindFlags(fs *pflag.FlagSet) {
-	pruneFlags.bigqueryFlags.BindFlags(fs)
+	fs.IntVar(&f.keepLast, "keep-last", 1, "Number of most recent snapshots to keep")
+	fs.DurationVar(&f.keepWithin, "keep-within", 0, "Also keep every snapshot created within this duration, e.g. 720h")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Report the snapshots that would be pruned without deleting anything")
+	fs.Float64Var(&f.minSizeRatio, "min-size-ratio", bigquery.DefaultMinSizeRatio,
+		"Refuse to prune when the newest snapshot has fewer rows than this fraction of the previous one")
+	fs.BoolVar(&f.force, "force", false, "Prune even when the newest snapshot looks truncated or incomplete")
+	f.bigqueryFlags.BindFlags(fs)
 }
 
 func init() {
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/mapping"
+	"github.com/openshift-eng/ci-test-mapping/pkg/server"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve test ownership lookups over an HTTP JSON API",
+	Run: func(cmd *cobra.Command, args []string) {
+		reg, err := newComponentRegistry()
+		if err != nil {
+			log.WithError(err).Fatal("could not load component registry")
+		}
+
+		mappings, err := mapping.LoadFile(serveFlags.mappingFile)
+		if err != nil {
+			log.WithError(err).Fatal("could not read mapping file")
+		}
+		log.Infof("loaded %d mappings from %s", len(mappings), serveFlags.mappingFile)
+
+		httpServer := &http.Server{
+			Addr:              serveFlags.listen,
+			Handler:           server.New(reg, mappings),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		// ListenAndServe returns as soon as shutdown starts, so wait for
+		// in-flight requests to drain before exiting
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				log.WithError(err).Warning("could not shut down cleanly")
+			}
+		}()
+
+		log.Infof("listening on %s", serveFlags.listen)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Fatal("could not serve")
+		}
+		<-done
+		log.Info("server shut down")
+	},
+}
+
+type ServeFlags struct {
+	listen      string
+	mappingFile string
+}
+
+var serveFlags = NewServeFlags()
+
+func NewServeFlags() *ServeFlags {
+	return &ServeFlags{}
+}
+
+func (f *ServeFlags) BindFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.listen, "listen", ":8080", "Address to listen on")
+	fs.StringVar(&f.mappingFile, "mapping-file", "mapping.json", "File containing the mappings to serve lookups from")
+}
+
+func init() {
+	serveFlags.BindFlags(serveCmd.Flags())
+	rootCmd.AddCommand(serveCmd)
+}
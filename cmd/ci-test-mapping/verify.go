@@ -6,8 +6,6 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/util/sets"
-
-	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
 )
 
 type VerifyFlags struct {
@@ -38,7 +36,10 @@ var verifyCmd = &cobra.Command{
 		}
 
 		logrus.Info("verifying all components have a mapping")
-		reg := registry.NewComponentRegistry()
+		reg, err := newComponentRegistry()
+		if err != nil {
+			logrus.WithError(err).Fatal("could not load component registry")
+		}
 		mappedJiraComponents := sets.New[string]()
 		for _, c := range reg.Components {
 			mappedJiraComponents.Insert(c.JiraComponents()...)
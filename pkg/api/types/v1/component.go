@@ -21,11 +21,31 @@ type Component interface {
 	JiraComponents() []string
 }
 
-// TestInfo is the input to the component owners with metadata about a test. It currently includes only
-// the test name and suite, but could in the future contain additional metadata.
+// TestInfo is the input to the component owners with metadata about a test. Name and suite are
+// always set; the remaining metadata is optional and only present when the test source provides it.
 type TestInfo struct {
 	Name  string
 	Suite string
+
+	// Repository is the source repository the test is defined in, e.g.
+	// openshift/origin.
+	Repository string `bigquery:"repository" json:",omitempty"`
+
+	// FilePath is the path of the file defining the test, relative to the
+	// root of Repository, e.g. test/extended/router/router.go.
+	FilePath string `bigquery:"file_path" json:",omitempty"`
+
+	// Labels are the Ginkgo labels applied to the test.
+	Labels []string `bigquery:"labels" json:",omitempty"`
+
+	// Lifecycle is the test's lifecycle, such as blocking or informing.
+	Lifecycle string `bigquery:"lifecycle" json:",omitempty"`
+
+	// Release is the OpenShift release the test was seen in, e.g. 4.16.
+	Release string `bigquery:"release" json:",omitempty"`
+
+	// Annotations are free-form key/value metadata from the test source.
+	Annotations map[string]string `bigquery:"-" json:",omitempty"`
 }
 
 const APIVersion = "v1"
@@ -90,6 +110,38 @@ type TestOwnership struct {
 	//
 	// Components do not need to set this value.
 	CreatedAt civil.DateTime `bigquery:"created_at" json:"-"`
+
+	// TestFingerprint is a hash of the TestInfo this record was identified
+	// from, and RegistryFingerprint a hash of the component registry that
+	// identified it. While both are unchanged, later runs carry the record
+	// over instead of identifying the test again.
+	//
+	// Components should not set these values.
+	TestFingerprint     string `bigquery:"test_fingerprint" json:",omitempty"`
+	RegistryFingerprint string `bigquery:"registry_fingerprint" json:",omitempty"`
+
+	// RunID identifies the mapping run that produced this record. Every
+	// record in a snapshot has the same run ID, and pushing a run that's
+	// already in the table is a no-op.
+	//
+	// Components should not set this value.
+	RunID string `bigquery:"run_id" json:",omitempty"`
+
+	// SnapshotSize is the number of records in the snapshot this record was
+	// pushed with, which tells whether a snapshot in the table is complete.
+	//
+	// Components should not set this value.
+	SnapshotSize int `bigquery:"snapshot_size" json:",omitempty"`
+
+	// ToolVersion and ToolCommit identify the ci-test-mapping build that
+	// produced the snapshot, TestSource the sources its tests were listed
+	// from, and InputTestCount how many tests they listed.
+	//
+	// Components should not set these values.
+	ToolVersion    string `bigquery:"tool_version" json:",omitempty"`
+	ToolCommit     string `bigquery:"tool_commit" json:",omitempty"`
+	TestSource     string `bigquery:"test_source" json:",omitempty"`
+	InputTestCount int    `bigquery:"input_test_count" json:",omitempty"`
 }
 
 var MappingTableSchema = bigquery.Schema{
@@ -142,4 +194,36 @@ var MappingTableSchema = bigquery.Schema{
 		Name: "created_at",
 		Type: bigquery.DateTimeFieldType,
 	},
+	{
+		Name: "test_fingerprint",
+		Type: bigquery.StringFieldType,
+	},
+	{
+		Name: "registry_fingerprint",
+		Type: bigquery.StringFieldType,
+	},
+	{
+		Name: "run_id",
+		Type: bigquery.StringFieldType,
+	},
+	{
+		Name: "snapshot_size",
+		Type: bigquery.IntegerFieldType,
+	},
+	{
+		Name: "tool_version",
+		Type: bigquery.StringFieldType,
+	},
+	{
+		Name: "tool_commit",
+		Type: bigquery.StringFieldType,
+	},
+	{
+		Name: "test_source",
+		Type: bigquery.StringFieldType,
+	},
+	{
+		Name: "input_test_count",
+		Type: bigquery.IntegerFieldType,
+	},
 }
@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+)
+
+// MappingRun records the provenance and statistics of one mapping run, so
+// snapshots can be compared without scanning the mapping table.
+type MappingRun struct {
+	// RunID and CreatedAt match the run's records in the mapping table.
+	RunID     string         `bigquery:"run_id"`
+	CreatedAt civil.DateTime `bigquery:"created_at"`
+
+	ToolVersion         string `bigquery:"tool_version"`
+	ToolCommit          string `bigquery:"tool_commit"`
+	RegistryFingerprint string `bigquery:"registry_fingerprint"`
+	TestSource          string `bigquery:"test_source"`
+
+	// InputTestCount is the number of tests listed from the test source, and
+	// Records the number of records in the snapshot.
+	InputTestCount int `bigquery:"input_test_count"`
+	Records        int `bigquery:"records"`
+
+	// Matched and Unmatched count the tests mapped to a component, or to the
+	// default one. Carried counts the records carried over from the previous
+	// run, Identified the tests identified again, and Obsolete the approved
+	// obsolete records kept from the previous run.
+	Matched    int `bigquery:"matched"`
+	Unmatched  int `bigquery:"unmatched"`
+	Carried    int `bigquery:"carried"`
+	Identified int `bigquery:"identified"`
+	Obsolete   int `bigquery:"obsolete"`
+
+	// DurationSeconds is how long mapping took, excluding the push.
+	DurationSeconds float64 `bigquery:"duration_seconds"`
+
+	// ComponentCounts counts the tests owned by each component, excluding
+	// obsolete ones.
+	ComponentCounts []ComponentCount `bigquery:"component_counts"`
+}
+
+type ComponentCount struct {
+	Component string `bigquery:"component" json:"component"`
+	Tests     int    `bigquery:"tests" json:"tests"`
+}
+
+var RunHistoryTableSchema = bigquery.Schema{
+	{Name: "run_id", Type: bigquery.StringFieldType},
+	{Name: "created_at", Type: bigquery.DateTimeFieldType},
+	{Name: "tool_version", Type: bigquery.StringFieldType},
+	{Name: "tool_commit", Type: bigquery.StringFieldType},
+	{Name: "registry_fingerprint", Type: bigquery.StringFieldType},
+	{Name: "test_source", Type: bigquery.StringFieldType},
+	{Name: "input_test_count", Type: bigquery.IntegerFieldType},
+	{Name: "records", Type: bigquery.IntegerFieldType},
+	{Name: "matched", Type: bigquery.IntegerFieldType},
+	{Name: "unmatched", Type: bigquery.IntegerFieldType},
+	{Name: "carried", Type: bigquery.IntegerFieldType},
+	{Name: "identified", Type: bigquery.IntegerFieldType},
+	{Name: "obsolete", Type: bigquery.IntegerFieldType},
+	{Name: "duration_seconds", Type: bigquery.FloatFieldType},
+	{
+		Name:     "component_counts",
+		Type:     bigquery.RecordFieldType,
+		Repeated: true,
+		Schema: bigquery.Schema{
+			{Name: "component", Type: bigquery.StringFieldType},
+			{Name: "tests", Type: bigquery.IntegerFieldType},
+		},
+	},
+}
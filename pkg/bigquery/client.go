@@ -15,8 +15,8 @@ import (
 )
 
 const (
-	projectName = "openshift-gce-devel"
-	datasetName = "ci_analysis_us"
+	DefaultProjectName = "openshift-gce-devel"
+	DefaultDatasetName = "ci_analysis_us"
 )
 
 type Client struct {
@@ -25,7 +25,8 @@ type Client struct {
 	datasetName string
 }
 
-func NewClient(ctx context.Context, googleServiceAccountCredentialFile, googleOAuthClientCredentialFile string) (*Client, error) {
+// NewClient returns a client for the given BigQuery project and dataset.
+func NewClient(ctx context.Context, projectName, datasetName, googleServiceAccountCredentialFile, googleOAuthClientCredentialFile string) (*Client, error) {
 	client := Client{
 		projectName: projectName,
 		datasetName: datasetName,
@@ -63,6 +64,16 @@ func NewClient(ctx context.Context, googleServiceAccountCredentialFile, googleOA
 	return &client, nil
 }
 
+// WrapClient returns a client for a dataset that uses an existing BigQuery
+// client, for tools that handle authentication themselves.
+func WrapClient(client *bigquery.Client, datasetName string) *Client {
+	return &Client{
+		bigquery:    client,
+		projectName: client.Project(),
+		datasetName: datasetName,
+	}
+}
+
 func getToken(config *oauth2.Config) *oauth2.Token {
 	tokenDir := os.Getenv("HOME")
 	if len(tokenDir) == 0 {
@@ -0,0 +1,172 @@
+// Package filestore is a file-backed stand-in for the BigQuery dataset used
+// by ci-test-mapping. Each table is a newline-delimited JSON file of rows,
+// keyed by column name, alongside a JSON schema file. It lets the bigquery
+// code paths run offline in tests and local development.
+package filestore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// ErrTableNotFound is returned when reading the schema of a table that
+// doesn't exist.
+var ErrTableNotFound = errors.New("table not found")
+
+// Dataset is a directory of tables.
+type Dataset struct {
+	Dir string
+
+	lock sync.Mutex
+}
+
+func NewDataset(dir string) (*Dataset, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Dataset{Dir: dir}, nil
+}
+
+func (d *Dataset) rowsFile(table string) string {
+	return filepath.Join(d.Dir, table+".ndjson")
+}
+
+func (d *Dataset) schemaFile(table string) string {
+	return filepath.Join(d.Dir, table+".schema.json")
+}
+
+// Schema returns the schema of a table, or ErrTableNotFound.
+func (d *Dataset) Schema(table string) (bigquery.Schema, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	data, err := os.ReadFile(d.schemaFile(table))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrTableNotFound, table)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return bigquery.SchemaFromJSON(data)
+}
+
+// SetSchema creates a table, or replaces its schema.
+func (d *Dataset) SetSchema(table string, schema bigquery.Schema) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	data, err := schema.ToJSONFields()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(d.schemaFile(table), data, 0o644) //nolint:gosec
+}
+
+// ReadRows returns every row of a table. A table without rows, or without a
+// rows file, is empty.
+func (d *Dataset) ReadRows(table string) ([]Row, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.readRows(table)
+}
+
+func (d *Dataset) readRows(table string) ([]Row, error) {
+	f, err := os.Open(d.rowsFile(table))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []Row
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var row Row
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", d.rowsFile(table), line, err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, scanner.Err()
+}
+
+// AppendRows adds rows to the end of a table.
+func (d *Dataset) AppendRows(table string, rows []Row) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	f, err := os.OpenFile(d.rowsFile(table), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := writeRows(f, rows); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReplaceRows atomically replaces the rows of a table with the ones for
+// which keep returns true, and returns how many were removed. keep may modify
+// the rows it keeps.
+func (d *Dataset) ReplaceRows(table string, keep func(Row) (bool, error)) (int, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	rows, err := d.readRows(table)
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []Row
+	for _, row := range rows {
+		ok, err := keep(row)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			kept = append(kept, row)
+		}
+	}
+
+	tmp, err := os.CreateTemp(d.Dir, table+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeRows(tmp, kept); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	return len(rows) - len(kept), os.Rename(tmp.Name(), d.rowsFile(table))
+}
+
+func writeRows(f *os.File, rows []Row) error {
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
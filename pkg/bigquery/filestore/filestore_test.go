@@ -0,0 +1,327 @@
+package filestore
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/civil"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
+)
+
+func TestMappingTableManager(t *testing.T) {
+	dataset, err := NewDataset(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := NewMappingTableManager(dataset, bigquery.DefaultMappingTableName)
+
+	if err := tm.Migrate(false); err != nil {
+		t.Fatalf("Migrate() returned unexpected err: %+v", err)
+	}
+	schema, err := dataset.Schema(bigquery.DefaultMappingTableName)
+	if err != nil {
+		t.Fatalf("Schema() returned unexpected err: %+v", err)
+	}
+	if !bigquery.SchemasEqual(schema, v1.MappingTableSchema) {
+		t.Errorf("Migrate() created schema %+v, want %+v", schema, v1.MappingTableSchema)
+	}
+
+	older := civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 1}, Time: civil.Time{Hour: 12}}
+	newer := civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 2}, Time: civil.Time{Hour: 12, Nanosecond: 500}}
+	snapshots := [][]v1.TestOwnership{
+		{
+			{ID: "1", Name: "a", Component: "A", Capabilities: []string{"x"}, CreatedAt: older},
+			{ID: "2", Name: "b", Component: "B", Capabilities: []string{"y"}, CreatedAt: older},
+		},
+		{
+			{ID: "1", Name: "a", Component: "A", Capabilities: []string{"x", "z"}, Priority: 1, CreatedAt: newer},
+		},
+	}
+	for _, snapshot := range snapshots {
+		if err := tm.PushMappings(snapshot); err != nil {
+			t.Fatalf("PushMappings() returned unexpected err: %+v", err)
+		}
+	}
+
+	mappings, err := tm.ListMappings()
+	if err != nil {
+		t.Fatalf("ListMappings() returned unexpected err: %+v", err)
+	}
+	if want := append(append([]v1.TestOwnership{}, snapshots[0]...), snapshots[1]...); !reflect.DeepEqual(mappings, want) {
+		t.Errorf("ListMappings() = %+v, want %+v", mappings, want)
+	}
+
+	latest, err := tm.ListLatestMappings()
+	if err != nil {
+		t.Fatalf("ListLatestMappings() returned unexpected err: %+v", err)
+	}
+	if !reflect.DeepEqual(latest, snapshots[1]) {
+		t.Errorf("ListLatestMappings() = %+v, want %+v", latest, snapshots[1])
+	}
+
+	snapshotList, err := tm.ListSnapshots()
+	if err != nil {
+		t.Fatalf("ListSnapshots() returned unexpected err: %+v", err)
+	}
+	wantSnapshots := []bigquery.Snapshot{{CreatedAt: newer, Rows: 1}, {CreatedAt: older, Rows: 2}}
+	if !reflect.DeepEqual(snapshotList, wantSnapshots) {
+		t.Errorf("ListSnapshots() = %+v, want %+v", snapshotList, wantSnapshots)
+	}
+
+	// The newest snapshot is much smaller than the previous one
+	if _, err := tm.PruneMappings(bigquery.RetentionPolicy{MinSizeRatio: bigquery.DefaultMinSizeRatio}, false); err == nil {
+		t.Errorf("PruneMappings() of a truncated snapshot succeeded, want an error")
+	}
+
+	plan, err := tm.PruneMappings(bigquery.RetentionPolicy{}, true)
+	if err != nil {
+		t.Fatalf("PruneMappings() returned unexpected err: %+v", err)
+	}
+	if plan.PrunedRows() != 2 {
+		t.Errorf("PruneMappings() dry run would prune %d rows, want 2", plan.PrunedRows())
+	}
+	if mappings, err = tm.ListMappings(); err != nil || len(mappings) != 3 {
+		t.Errorf("ListMappings() after a dry run returned %d rows, %v, want 3", len(mappings), err)
+	}
+
+	if _, err := tm.PruneMappings(bigquery.RetentionPolicy{}, false); err != nil {
+		t.Fatalf("PruneMappings() returned unexpected err: %+v", err)
+	}
+	mappings, err = tm.ListMappings()
+	if err != nil {
+		t.Fatalf("ListMappings() returned unexpected err: %+v", err)
+	}
+	if !reflect.DeepEqual(mappings, snapshots[1]) {
+		t.Errorf("ListMappings() after prune = %+v, want %+v", mappings, snapshots[1])
+	}
+}
+
+func TestMappingTableManagerRunID(t *testing.T) {
+	dataset, err := NewDataset(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := NewMappingTableManager(dataset, bigquery.DefaultMappingTableName)
+	if err := tm.Migrate(false); err != nil {
+		t.Fatalf("Migrate() returned unexpected err: %+v", err)
+	}
+
+	createdAt := civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 1}, Time: civil.Time{Hour: 12}}
+	snapshot := []v1.TestOwnership{
+		{ID: "1", Name: "a", Component: "A", CreatedAt: createdAt, RunID: "run-1"},
+		{ID: "2", Name: "b", Component: "B", CreatedAt: createdAt, RunID: "run-1"},
+	}
+	for i := 0; i < 2; i++ {
+		if err := tm.PushMappings(snapshot); err != nil {
+			t.Fatalf("PushMappings() returned unexpected err: %+v", err)
+		}
+	}
+	mappings, err := tm.ListMappings()
+	if err != nil {
+		t.Fatalf("ListMappings() returned unexpected err: %+v", err)
+	}
+	if !reflect.DeepEqual(mappings, snapshot) {
+		t.Errorf("ListMappings() after pushing a run twice = %+v, want %+v", mappings, snapshot)
+	}
+
+	// Retrying a partially pushed run adds only the missing rows, with the
+	// CreatedAt of the first attempt rather than the retry's
+	pushedAt := civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 3}}
+	partial := []v1.TestOwnership{
+		{ID: "1", Name: "a", Component: "A", CreatedAt: pushedAt, RunID: "run-4", SnapshotSize: 2},
+		{ID: "2", Name: "b", Component: "B", CreatedAt: pushedAt, RunID: "run-4", SnapshotSize: 2},
+	}
+	row, err := EncodeRow(&partial[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dataset.AppendRows(bigquery.DefaultMappingTableName, []Row{row}); err != nil {
+		t.Fatal(err)
+	}
+	retried := bigquery.StampCreatedAt(partial, civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 4}})
+	if err := tm.PushMappings(retried); err != nil {
+		t.Fatalf("PushMappings() of a partially pushed run returned unexpected err: %+v", err)
+	}
+	mappings, err = tm.ListMappings()
+	if err != nil {
+		t.Fatalf("ListMappings() returned unexpected err: %+v", err)
+	}
+	if want := append(append([]v1.TestOwnership{}, snapshot...), partial...); !reflect.DeepEqual(mappings, want) {
+		t.Errorf("ListMappings() after completing a partial run = %+v, want %+v", mappings, want)
+	}
+	latest, err := tm.ListLatestMappings()
+	if err != nil {
+		t.Fatalf("ListLatestMappings() returned unexpected err: %+v", err)
+	}
+	if !reflect.DeepEqual(latest, partial) {
+		t.Errorf("ListLatestMappings() after completing a partial run = %+v, want %+v", latest, partial)
+	}
+	snapshots, err := tm.ListSnapshots()
+	if err != nil {
+		t.Fatalf("ListSnapshots() returned unexpected err: %+v", err)
+	}
+	if len(snapshots) != 2 || !snapshots[0].Complete() || snapshots[0].RunID != "run-4" {
+		t.Errorf("ListSnapshots() after completing a partial run = %+v, want run-4 complete and newest", snapshots)
+	}
+
+	mixed := []v1.TestOwnership{
+		{ID: "1", Name: "a", Component: "A", CreatedAt: createdAt, RunID: "run-2"},
+		{ID: "2", Name: "b", Component: "B", CreatedAt: createdAt, RunID: "run-3"},
+	}
+	if err := tm.PushMappings(mixed); err == nil {
+		t.Errorf("PushMappings() of records from several runs succeeded, want an error")
+	}
+
+	older := v1.MappingRun{RunID: "run-0", CreatedAt: createdAt, Records: 1}
+	run := v1.MappingRun{
+		RunID:           "run-1",
+		CreatedAt:       civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 2}},
+		ToolCommit:      "abc",
+		Records:         2,
+		DurationSeconds: 1.5,
+		ComponentCounts: []v1.ComponentCount{{Component: "A", Tests: 1}, {Component: "B", Tests: 1}},
+	}
+	for _, r := range []v1.MappingRun{older, run, run} {
+		if err := tm.PushRun(r); err != nil {
+			t.Fatalf("PushRun() returned unexpected err: %+v", err)
+		}
+	}
+	runs, err := tm.ListRuns()
+	if err != nil {
+		t.Fatalf("ListRuns() returned unexpected err: %+v", err)
+	}
+	if want := []v1.MappingRun{run, older}; !reflect.DeepEqual(runs, want) {
+		t.Errorf("ListRuns() = %+v, want %+v", runs, want)
+	}
+}
+
+func TestMappingTableManagerMigrate(t *testing.T) {
+	dataset, err := NewDataset(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := NewMappingTableManager(dataset, bigquery.DefaultMappingTableName)
+
+	// A table created before migrations were recorded, with the initial
+	// schema and rows
+	initial, err := bigquery.MigratedSchema(bigquery.MappingTableMigrations[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dataset.SetSchema(bigquery.DefaultMappingTableName, initial); err != nil {
+		t.Fatal(err)
+	}
+	if err := dataset.AppendRows(bigquery.DefaultMappingTableName, []Row{
+		{"id": json.RawMessage(`"1"`), "name": json.RawMessage(`"a"`), "created_at": json.RawMessage(`"2023-05-01T12:00:00"`)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := tm.Migrate(false); err != nil {
+			t.Fatalf("Migrate() returned unexpected err: %+v", err)
+		}
+	}
+	schema, err := dataset.Schema(bigquery.DefaultMappingTableName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bigquery.SchemasEqual(schema, v1.MappingTableSchema) {
+		t.Errorf("Migrate() updated schema to %+v, want %+v", schema, v1.MappingTableSchema)
+	}
+	mappings, err := tm.ListMappings()
+	if err != nil {
+		t.Fatalf("ListMappings() returned unexpected err: %+v", err)
+	}
+	if len(mappings) != 1 || mappings[0].RunID != "legacy-20230501T120000.000000" {
+		t.Errorf("Migrate() backfilled %+v, want a legacy run ID", mappings)
+	}
+
+	target := &tableMigrationTarget{dataset: dataset, tableName: bigquery.DefaultMappingTableName}
+	applied, err := target.AppliedMigrations()
+	if err != nil {
+		t.Fatalf("AppliedMigrations() returned unexpected err: %+v", err)
+	}
+	if len(applied) != len(bigquery.MappingTableMigrations) {
+		t.Errorf("Migrate() recorded %d migrations, want %d", len(applied), len(bigquery.MappingTableMigrations))
+	}
+
+	createdAt := civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 2}, Time: civil.Time{Hour: 12}}
+	if err := tm.PushMappings([]v1.TestOwnership{{ID: "2", Name: "b", CreatedAt: createdAt, TestFingerprint: "f", SnapshotSize: 1}}); err != nil {
+		t.Fatalf("PushMappings() returned unexpected err: %+v", err)
+	}
+
+	destructive := append(append([]bigquery.Migration{}, bigquery.MappingTableMigrations...), bigquery.Migration{
+		ID:            "9999-destructive",
+		APIVersion:    v1.APIVersion,
+		RenameColumns: []bigquery.ColumnRename{{From: "snapshot_size", To: "expected_size"}},
+		DropColumns:   []string{"test_fingerprint"},
+	})
+	want, err := bigquery.MigratedSchema(destructive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bigquery.RunMigrations(target, destructive, want, false); err == nil {
+		t.Errorf("RunMigrations() applied a destructive migration without allowing it")
+	}
+	if err := bigquery.RunMigrations(target, destructive, want, true); err != nil {
+		t.Fatalf("RunMigrations() returned unexpected err: %+v", err)
+	}
+	rows, err := dataset.ReadRows(bigquery.DefaultMappingTableName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rows[1]["expected_size"]) != "1" {
+		t.Errorf("RunMigrations() didn't rename snapshot_size in %v", rows[1])
+	}
+	if _, ok := rows[1]["test_fingerprint"]; ok {
+		t.Errorf("RunMigrations() didn't drop test_fingerprint from %v", rows[1])
+	}
+
+	// The table was migrated by a newer version
+	if err := tm.Migrate(false); err == nil {
+		t.Errorf("Migrate() of a table with unknown migrations succeeded, want an error")
+	}
+}
+
+func TestTestTableManager(t *testing.T) {
+	dataset, err := NewDataset(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := NewTestTableManager(dataset, bigquery.DefaultTestTableName, &bigquery.TestTableConfig{
+		Suites:             []string{"openshift-tests", "Cluster upgrade"},
+		IgnoredTests:       []string{"Build image%", "%was\\_not%"},
+		IgnoredTestRegexes: []string{`^step graph\.`},
+	})
+	if err := tm.InsertRows([]JUnitRow{
+		{TestName: "[sig-storage] b", TestSuite: "openshift-tests"},
+		{TestName: "[sig-storage] a", TestSuite: "openshift-tests", Release: "4.15"},
+		{TestName: "[sig-storage] a", TestSuite: "openshift-tests", Release: "4.16", FilePath: "test/extended/storage/a.go", Labels: []string{"Serial"}},
+		{TestName: "[sig-storage] a", TestSuite: "Cluster upgrade"},
+		{TestName: "[sig-storage] a", TestSuite: "other-suite"},
+		{TestName: "Build image foo from the repository", TestSuite: "openshift-tests"},
+		{TestName: "pod was_not OOMKilled", TestSuite: "openshift-tests"},
+		{TestName: "pod wasXnot OOMKilled", TestSuite: "openshift-tests"},
+		{TestName: "step graph.Run", TestSuite: "openshift-tests"},
+	}); err != nil {
+		t.Fatalf("InsertRows() returned unexpected err: %+v", err)
+	}
+
+	tests, err := tm.ListTests()
+	if err != nil {
+		t.Fatalf("ListTests() returned unexpected err: %+v", err)
+	}
+	want := []v1.TestInfo{
+		{Name: "[sig-storage] a", Suite: "openshift-tests", Release: "4.16", FilePath: "test/extended/storage/a.go", Labels: []string{"Serial"}},
+		{Name: "[sig-storage] a", Suite: "Cluster upgrade"},
+		{Name: "[sig-storage] b", Suite: "openshift-tests"},
+		{Name: "pod wasXnot OOMKilled", Suite: "openshift-tests"},
+	}
+	if !reflect.DeepEqual(tests, want) {
+		t.Errorf("ListTests() = %+v, want %+v", tests, want)
+	}
+}
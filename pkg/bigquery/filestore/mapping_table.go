@@ -0,0 +1,231 @@
+package filestore
+
+import (
+	"sort"
+	"time"
+
+	"cloud.google.com/go/civil"
+	log "github.com/sirupsen/logrus"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
+	"github.com/openshift-eng/ci-test-mapping/pkg/mapping"
+)
+
+// MappingTableManager implements bigquery.MappingStore on a Dataset.
+type MappingTableManager struct {
+	dataset   *Dataset
+	tableName string
+}
+
+var _ bigquery.MappingStore = &MappingTableManager{}
+
+func NewMappingTableManager(dataset *Dataset, tableName string) *MappingTableManager {
+	return &MappingTableManager{
+		dataset:   dataset,
+		tableName: tableName,
+	}
+}
+
+func (tm *MappingTableManager) Migrate(allowDestructive bool) error {
+	if err := bigquery.RunMigrations(&tableMigrationTarget{
+		dataset:   tm.dataset,
+		tableName: tm.tableName,
+	}, bigquery.MappingTableMigrations, v1.MappingTableSchema, allowDestructive); err != nil {
+		return err
+	}
+	return bigquery.RunMigrations(&tableMigrationTarget{
+		dataset:   tm.dataset,
+		tableName: tm.runHistoryTable(),
+	}, bigquery.RunHistoryTableMigrations, v1.RunHistoryTableSchema, allowDestructive)
+}
+
+func (tm *MappingTableManager) ListMappings() ([]v1.TestOwnership, error) {
+	rows, err := tm.dataset.ReadRows(tm.tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]v1.TestOwnership, 0, len(rows))
+	for _, row := range rows {
+		var testOwnership v1.TestOwnership
+		if err := DecodeRow(row, &testOwnership); err != nil {
+			return nil, err
+		}
+		results = append(results, testOwnership)
+	}
+
+	return results, nil
+}
+
+// ListLatestMappings reads the whole table, there being no partitions to
+// skip.
+func (tm *MappingTableManager) ListLatestMappings() ([]v1.TestOwnership, error) {
+	mappings, err := tm.ListMappings()
+	if err != nil {
+		return nil, err
+	}
+	return mapping.Latest(mappings), nil
+}
+
+// PushMappings appends the snapshot atomically, like the load and merge push
+// modes do in BigQuery, so the push mode doesn't matter. A run that's
+// partially in the table is completed, like the merge push mode does, with
+// the added rows keeping the run's CreatedAt.
+func (tm *MappingTableManager) PushMappings(mappings []v1.TestOwnership) error {
+	runID, err := bigquery.SnapshotRunID(mappings)
+	if err != nil {
+		return err
+	}
+	if runID != "" {
+		existing, err := tm.ListMappings()
+		if err != nil {
+			return err
+		}
+		type key struct{ id, name, suite string }
+		pushed := make(map[key]bool)
+		var createdAt civil.DateTime
+		for i := range existing {
+			if existing[i].RunID == runID {
+				pushed[key{existing[i].ID, existing[i].Name, existing[i].Suite}] = true
+				if createdAt.IsZero() || existing[i].CreatedAt.Before(createdAt) {
+					createdAt = existing[i].CreatedAt
+				}
+			}
+		}
+
+		switch bigquery.PlanPush(len(pushed), len(mappings)) {
+		case bigquery.PushSkip:
+			log.Infof("run %q was already pushed to %q, skipping", runID, tm.tableName)
+			return nil
+		case bigquery.PushComplete:
+			log.Warningf("run %q was partially pushed (%d of %d rows), adding the missing rows",
+				runID, len(pushed), len(mappings))
+			var missing []v1.TestOwnership
+			for i := range mappings {
+				if !pushed[key{mappings[i].ID, mappings[i].Name, mappings[i].Suite}] {
+					missing = append(missing, mappings[i])
+				}
+			}
+			mappings = bigquery.StampCreatedAt(missing, createdAt)
+		}
+	}
+
+	rows := make([]Row, 0, len(mappings))
+	for i := range mappings {
+		row, err := EncodeRow(&mappings[i])
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+
+	if err := tm.dataset.AppendRows(tm.tableName, rows); err != nil {
+		return err
+	}
+	log.Infof("added %d rows to mapping table", len(rows))
+	return nil
+}
+
+func (tm *MappingTableManager) ListSnapshots() ([]bigquery.Snapshot, error) {
+	mappings, err := tm.ListMappings()
+	if err != nil {
+		return nil, err
+	}
+
+	byCreatedAt := make(map[civil.DateTime]*bigquery.Snapshot)
+	var snapshots []*bigquery.Snapshot
+	for i := range mappings {
+		s, ok := byCreatedAt[mappings[i].CreatedAt]
+		if !ok {
+			s = &bigquery.Snapshot{CreatedAt: mappings[i].CreatedAt, RunID: mappings[i].RunID}
+			byCreatedAt[mappings[i].CreatedAt] = s
+			snapshots = append(snapshots, s)
+		}
+		s.Rows++
+		if mappings[i].SnapshotSize > s.ExpectedRows {
+			s.ExpectedRows = mappings[i].SnapshotSize
+		}
+	}
+
+	result := make([]bigquery.Snapshot, 0, len(snapshots))
+	for _, s := range snapshots {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (tm *MappingTableManager) PruneMappings(policy bigquery.RetentionPolicy, dryRun bool) (*bigquery.PrunePlan, error) {
+	snapshots, err := tm.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	plan, err := policy.Plan(snapshots, time.Now())
+	if err != nil || dryRun || len(plan.Prune) == 0 {
+		return plan, err
+	}
+
+	cutoff := plan.Cutoff()
+	pruned, err := tm.dataset.ReplaceRows(tm.tableName, func(row Row) (bool, error) {
+		var testOwnership v1.TestOwnership
+		if err := DecodeRow(row, &testOwnership); err != nil {
+			return false, err
+		}
+		return !testOwnership.CreatedAt.Before(cutoff), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("pruned %d rows from mapping table", pruned)
+	return plan, nil
+}
+
+func (tm *MappingTableManager) runHistoryTable() string {
+	return tm.tableName + bigquery.RunHistoryTableSuffix
+}
+
+func (tm *MappingTableManager) PushRun(run v1.MappingRun) error {
+	runs, err := tm.ListRuns()
+	if err != nil {
+		return err
+	}
+	for i := range runs {
+		if runs[i].RunID == run.RunID {
+			log.Infof("run %q was already recorded in %q, skipping", run.RunID, tm.runHistoryTable())
+			return nil
+		}
+	}
+
+	row, err := EncodeRow(&run)
+	if err != nil {
+		return err
+	}
+	if err := tm.dataset.AppendRows(tm.runHistoryTable(), []Row{row}); err != nil {
+		return err
+	}
+	log.Infof("recorded run %q in %q", run.RunID, tm.runHistoryTable())
+	return nil
+}
+
+func (tm *MappingTableManager) ListRuns() ([]v1.MappingRun, error) {
+	rows, err := tm.dataset.ReadRows(tm.runHistoryTable())
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]v1.MappingRun, 0, len(rows))
+	for _, row := range rows {
+		var run v1.MappingRun
+		if err := DecodeRow(row, &run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
@@ -0,0 +1,176 @@
+package filestore
+
+import (
+	"errors"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	log "github.com/sirupsen/logrus"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	bq "github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
+)
+
+// tableMigrationTarget applies migrations to a table in a Dataset, recording
+// them in a metadata table next to it. Backfills apply to mapping records.
+type tableMigrationTarget struct {
+	dataset   *Dataset
+	tableName string
+}
+
+var _ bq.MigrationTarget = &tableMigrationTarget{}
+
+func (t *tableMigrationTarget) Name() string {
+	return t.tableName
+}
+
+func (t *tableMigrationTarget) Schema() (bigquery.Schema, bool, error) {
+	schema, err := t.dataset.Schema(t.tableName)
+	if errors.Is(err, ErrTableNotFound) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return schema, true, nil
+}
+
+func (t *tableMigrationTarget) CreateTable(schema bigquery.Schema) error {
+	if err := t.dataset.SetSchema(t.tableName, schema); err != nil {
+		return err
+	}
+	log.Infof("table created %q", t.tableName)
+	return nil
+}
+
+func (t *tableMigrationTarget) UpdateSchema(schema bigquery.Schema) error {
+	if err := t.dataset.SetSchema(t.tableName, schema); err != nil {
+		return err
+	}
+	log.Infof("table schema updated %q", t.tableName)
+	return nil
+}
+
+func (t *tableMigrationTarget) RenameColumn(from, to string) error {
+	if err := t.updateSchema(func(schema bigquery.Schema) bigquery.Schema {
+		for _, field := range schema {
+			if strings.EqualFold(field.Name, from) {
+				field.Name = to
+			}
+		}
+		return schema
+	}); err != nil {
+		return err
+	}
+	return t.updateRows(func(row Row) error {
+		if key, ok := findColumn(row, from); ok {
+			row[to] = row[key]
+			delete(row, key)
+		}
+		return nil
+	})
+}
+
+func (t *tableMigrationTarget) DropColumn(name string) error {
+	if err := t.updateSchema(func(schema bigquery.Schema) bigquery.Schema {
+		var kept bigquery.Schema
+		for _, field := range schema {
+			if !strings.EqualFold(field.Name, name) {
+				kept = append(kept, field)
+			}
+		}
+		return kept
+	}); err != nil {
+		return err
+	}
+	return t.updateRows(func(row Row) error {
+		if key, ok := findColumn(row, name); ok {
+			delete(row, key)
+		}
+		return nil
+	})
+}
+
+// Backfill sets the column on the rows where it's missing or null, keeping
+// their other columns as they are.
+func (t *tableMigrationTarget) Backfill(backfill bq.Backfill) error {
+	return t.updateRows(func(row Row) error {
+		if key, ok := findColumn(row, backfill.Column); ok && string(row[key]) != "null" {
+			return nil
+		}
+		var testOwnership v1.TestOwnership
+		if err := DecodeRow(row, &testOwnership); err != nil {
+			return err
+		}
+		backfill.Apply(&testOwnership)
+		filled, err := EncodeRow(&testOwnership)
+		if err != nil {
+			return err
+		}
+		if key, ok := findColumn(filled, backfill.Column); ok {
+			row[key] = filled[key]
+		}
+		return nil
+	})
+}
+
+func (t *tableMigrationTarget) AppliedMigrations() ([]bq.MigrationRecord, error) {
+	table := t.tableName + bq.MigrationsTableSuffix
+	if _, err := t.dataset.Schema(table); errors.Is(err, ErrTableNotFound) {
+		log.Infof("creating migrations table %q", table)
+		return nil, t.dataset.SetSchema(table, bq.MigrationsTableSchema)
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := t.dataset.ReadRows(table)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]bq.MigrationRecord, 0, len(rows))
+	for _, row := range rows {
+		var record bq.MigrationRecord
+		if err := DecodeRow(row, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (t *tableMigrationTarget) RecordMigration(record bq.MigrationRecord) error {
+	row, err := EncodeRow(&record)
+	if err != nil {
+		return err
+	}
+	return t.dataset.AppendRows(t.tableName+bq.MigrationsTableSuffix, []Row{row})
+}
+
+func (t *tableMigrationTarget) updateSchema(update func(bigquery.Schema) bigquery.Schema) error {
+	schema, err := t.dataset.Schema(t.tableName)
+	if err != nil {
+		return err
+	}
+	return t.UpdateSchema(update(schema))
+}
+
+// updateRows rewrites every row of the table in place.
+func (t *tableMigrationTarget) updateRows(update func(Row) error) error {
+	_, err := t.dataset.ReplaceRows(t.tableName, func(row Row) (bool, error) {
+		return true, update(row)
+	})
+	return err
+}
+
+// findColumn finds a row's key for a column, case-insensitively like
+// DecodeRow.
+func findColumn(row Row, name string) (string, bool) {
+	if _, ok := row[name]; ok {
+		return name, true
+	}
+	for key := range row {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,103 @@
+package filestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"cloud.google.com/go/civil"
+)
+
+// Row is a single table row, keyed by column name.
+type Row map[string]json.RawMessage
+
+var dateTimeType = reflect.TypeOf(civil.DateTime{})
+
+// columnName returns the BigQuery column a struct field is stored in, the
+// same way the BigQuery client does: the `bigquery` tag if present, or the
+// field name.
+func columnName(field *reflect.StructField) string {
+	tag := field.Tag.Get("bigquery")
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// EncodeRow converts a struct, or pointer to a struct, into a row.
+func EncodeRow(v interface{}) (Row, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot encode %T as a row", v)
+	}
+
+	row := make(Row)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() || columnName(&field) == "-" {
+			continue
+		}
+
+		var data []byte
+		var err error
+		if field.Type == dateTimeType {
+			data, err = json.Marshal(value.Field(i).Interface().(civil.DateTime).String())
+		} else {
+			data, err = json.Marshal(value.Field(i).Interface())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode column %s: %w", columnName(&field), err)
+		}
+		row[columnName(&field)] = data
+	}
+
+	return row, nil
+}
+
+// DecodeRow fills the struct pointed to by v from a row. Columns are matched
+// to fields case-insensitively, like the BigQuery client does; columns with
+// no matching field are ignored.
+func DecodeRow(row Row, v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode a row into %T", v)
+	}
+	value = value.Elem()
+
+	columns := make(map[string]json.RawMessage, len(row))
+	for name, data := range row {
+		columns[strings.ToLower(name)] = data
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := columnName(&field)
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		data, ok := columns[strings.ToLower(name)]
+		if !ok || string(data) == "null" {
+			continue
+		}
+
+		if field.Type == dateTimeType {
+			var s string
+			if err := json.Unmarshal(data, &s); err != nil {
+				return fmt.Errorf("cannot decode column %s: %w", name, err)
+			}
+			dt, err := civil.ParseDateTime(s)
+			if err != nil {
+				return fmt.Errorf("cannot decode column %s: %w", name, err)
+			}
+			value.Field(i).Set(reflect.ValueOf(dt))
+			continue
+		}
+		if err := json.Unmarshal(data, value.Field(i).Addr().Interface()); err != nil {
+			return fmt.Errorf("cannot decode column %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package filestore
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
+)
+
+// JUnitRow is a row of the junit table, with only the columns the mapper
+// reads. Use it to seed a Dataset for tests. The metadata columns are
+// optional.
+type JUnitRow struct {
+	TestName  string `bigquery:"test_name"`
+	TestSuite string `bigquery:"testsuite"`
+
+	Repository string   `bigquery:"repository"`
+	FilePath   string   `bigquery:"file_path"`
+	Labels     []string `bigquery:"labels"`
+	Lifecycle  string   `bigquery:"lifecycle"`
+	Release    string   `bigquery:"release"`
+}
+
+// TestTableManager implements bigquery.TestLister on a Dataset, applying the
+// same suite allowlist and ignore patterns as the BigQuery query.
+type TestTableManager struct {
+	dataset   *Dataset
+	tableName string
+	config    *bigquery.TestTableConfig
+}
+
+var _ bigquery.TestLister = &TestTableManager{}
+
+func NewTestTableManager(dataset *Dataset, tableName string, config *bigquery.TestTableConfig) *TestTableManager {
+	if config == nil {
+		config = bigquery.DefaultTestTableConfig()
+	}
+
+	return &TestTableManager{
+		dataset:   dataset,
+		tableName: tableName,
+		config:    config,
+	}
+}
+
+// InsertRows seeds the junit table.
+func (tm *TestTableManager) InsertRows(rows []JUnitRow) error {
+	encoded := make([]Row, 0, len(rows))
+	for i := range rows {
+		row, err := EncodeRow(&rows[i])
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, row)
+	}
+	return tm.dataset.AppendRows(tm.tableName, encoded)
+}
+
+func (tm *TestTableManager) ListTests() ([]v1.TestInfo, error) {
+	rows, err := tm.dataset.ReadRows(tm.tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	suites := make(map[string]bool, len(tm.config.Suites))
+	for _, suite := range tm.config.Suites {
+		suites[suite] = true
+	}
+	var ignored []*regexp.Regexp
+	for _, pattern := range tm.config.IgnoredTests {
+		ignored = append(ignored, likeToRegexp(pattern))
+	}
+	for _, expr := range tm.config.IgnoredTestRegexes {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		ignored = append(ignored, re)
+	}
+
+	type key struct{ name, suite string }
+	seen := make(map[key]int)
+	var results []v1.TestInfo
+rows:
+	for _, row := range rows {
+		var junit JUnitRow
+		if err := DecodeRow(row, &junit); err != nil {
+			return nil, err
+		}
+		if !suites[junit.TestSuite] {
+			continue
+		}
+		for _, re := range ignored {
+			if re.MatchString(junit.TestName) {
+				continue rows
+			}
+		}
+
+		k := key{name: junit.TestName, suite: junit.TestSuite}
+		if i, ok := seen[k]; ok {
+			mergeMetadata(&results[i], &junit)
+			continue
+		}
+		seen[k] = len(results)
+		results = append(results, v1.TestInfo{
+			Name:       junit.TestName,
+			Suite:      junit.TestSuite,
+			Repository: junit.Repository,
+			FilePath:   junit.FilePath,
+			Labels:     junit.Labels,
+			Lifecycle:  junit.Lifecycle,
+			Release:    junit.Release,
+		})
+	}
+
+	// Match the BigQuery query's ORDER BY name, testsuite DESC
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Name != results[j].Name {
+			return results[i].Name < results[j].Name
+		}
+		return results[i].Suite > results[j].Suite
+	})
+
+	return results, nil
+}
+
+// mergeMetadata aggregates the metadata of another row for the same test the
+// way the BigQuery query does: any value of each column, and the highest
+// release.
+func mergeMetadata(testInfo *v1.TestInfo, junit *JUnitRow) {
+	if testInfo.Repository == "" {
+		testInfo.Repository = junit.Repository
+	}
+	if testInfo.FilePath == "" {
+		testInfo.FilePath = junit.FilePath
+	}
+	if len(testInfo.Labels) == 0 {
+		testInfo.Labels = junit.Labels
+	}
+	if testInfo.Lifecycle == "" {
+		testInfo.Lifecycle = junit.Lifecycle
+	}
+	if junit.Release > testInfo.Release {
+		testInfo.Release = junit.Release
+	}
+}
+
+// likeToRegexp converts a SQL LIKE pattern into an anchored regular
+// expression: % matches any run of characters, _ matches one character, and
+// a backslash escapes the next character.
+func likeToRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString(`(?s)^`)
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(`.*`)
+		case r == '_':
+			b.WriteString(`.`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(`$`)
+	return regexp.MustCompile(b.String())
+}
@@ -2,11 +2,18 @@ package bigquery
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
@@ -14,55 +21,141 @@ import (
 	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
 )
 
-const mappingTableName = "component_mapping"
+const DefaultMappingTableName = "component_mapping"
+
+// LatestViewSuffix is appended to the mapping table's name to name the view
+// of its newest snapshot.
+const LatestViewSuffix = "_latest"
+
+// The mapping table is partitioned by day on created_at, so pruning and
+// reading recent snapshots only touch the partitions they need, and
+// clustered by component, for consumers looking up a component's tests.
+var (
+	mappingTablePartitioning = &bigquery.TimePartitioning{
+		Type:  bigquery.DayPartitioningType,
+		Field: "created_at",
+	}
+	mappingTableClustering = &bigquery.Clustering{
+		Fields: []string{"component"},
+	}
+)
+
+// nonIdentifierRegexp matches the characters that can't be used in a table
+// name.
+var nonIdentifierRegexp = regexp.MustCompile(`[^a-zA-Z0-9_]`)
 
 type MappingTableManager struct {
-	ctx    context.Context
-	client *Client
+	ctx       context.Context
+	client    *Client
+	tableName string
+	pushMode  string
 }
 
-func NewMappingTableManager(ctx context.Context, client *Client) *MappingTableManager {
+// NewMappingTableManager returns a manager for the mapping table, pushing
+// snapshots in the given push mode, one of PushModes.
+func NewMappingTableManager(ctx context.Context, client *Client, tableName, pushMode string) *MappingTableManager {
 	return &MappingTableManager{
-		ctx:    ctx,
-		client: client,
+		ctx:       ctx,
+		client:    client,
+		tableName: tableName,
+		pushMode:  pushMode,
 	}
 }
 
-func (tm *MappingTableManager) Migrate() error {
-	dataset := tm.client.bigquery.Dataset(tm.client.datasetName)
-	table := dataset.Table(mappingTableName)
+// Migrate applies the pending migrations to the mapping and run-history
+// tables, creating them if needed, and maintains the mapping table's
+// clustering and latest view. Destructive migrations are only applied with
+// allowDestructive.
+func (tm *MappingTableManager) Migrate(allowDestructive bool) error {
+	if err := RunMigrations(&tableMigrationTarget{
+		ctx:              tm.ctx,
+		client:           tm.client,
+		table:            tm.Table(),
+		timePartitioning: mappingTablePartitioning,
+		clustering:       mappingTableClustering,
+	}, MappingTableMigrations, v1.MappingTableSchema, allowDestructive); err != nil {
+		return err
+	}
+	if err := tm.updateLayout(); err != nil {
+		return err
+	}
+	if err := tm.updateLatestView(); err != nil {
+		return err
+	}
+	return RunMigrations(&tableMigrationTarget{
+		ctx:    tm.ctx,
+		client: tm.client,
+		table:  tm.RunHistoryTable(),
+	}, RunHistoryTableMigrations, v1.RunHistoryTableSchema, allowDestructive)
+}
 
+// updateLayout clusters a mapping table created before it was clustered.
+// Partitioning can't be added to an existing table, so an unpartitioned
+// table is only reported.
+func (tm *MappingTableManager) updateLayout() error {
+	table := tm.Table()
 	md, err := table.Metadata(tm.ctx)
-	// Create table if it doesn't exist
-	if gbErr, ok := err.(*googleapi.Error); err != nil && ok && gbErr.Code == 404 {
-		log.Infof("table doesn't existing, creating table %q", mappingTableName)
-		if err := table.Create(tm.ctx, &bigquery.TableMetadata{
-			Schema: v1.MappingTableSchema,
-		}); err != nil {
+	if err != nil {
+		return err
+	}
+	if md.TimePartitioning == nil {
+		log.Warningf("table %q isn't partitioned on %s; recreate it to partition it",
+			table.TableID, mappingTablePartitioning.Field)
+	}
+	if md.Clustering == nil || !reflect.DeepEqual(md.Clustering.Fields, mappingTableClustering.Fields) {
+		if _, err := table.Update(tm.ctx, bigquery.TableMetadataToUpdate{Clustering: mappingTableClustering}, md.ETag); err != nil {
 			return err
 		}
-		log.Infof("table created %q", mappingTableName)
+		log.Infof("table %q clustered by %s", table.TableID, strings.Join(mappingTableClustering.Fields, ", "))
+	}
+	return nil
+}
+
+// updateLatestView creates the view of the newest snapshot, or updates its
+// query.
+func (tm *MappingTableManager) updateLatestView() error {
+	view := tm.LatestView()
+	query := tm.latestMappingsSQL()
+
+	md, err := view.Metadata(tm.ctx)
+	if gbErr, ok := err.(*googleapi.Error); ok && gbErr.Code == 404 {
+		if err := view.Create(tm.ctx, &bigquery.TableMetadata{ViewQuery: query}); err != nil {
+			return err
+		}
+		log.Infof("view created %q", view.TableID)
+		return nil
 	} else if err != nil {
 		return err
-	} else {
-		if !schemasEqual(md.Schema, v1.MappingTableSchema) {
-			if _, err := table.Update(tm.ctx, bigquery.TableMetadataToUpdate{Schema: v1.MappingTableSchema}, md.ETag); err != nil {
-				log.WithError(err).Errorf("failed to update table schema for %q", mappingTableName)
-				return err
-			}
-			log.Infof("table schema updated %q", mappingTableName)
-		} else {
-			log.Infof("table schema is up-to-date %q", mappingTableName)
-		}
 	}
 
+	if md.ViewQuery != query {
+		if _, err := view.Update(tm.ctx, bigquery.TableMetadataToUpdate{ViewQuery: query}, md.ETag); err != nil {
+			return err
+		}
+		log.Infof("view updated %q", view.TableID)
+	}
 	return nil
 }
 
+// latestMappingsSQL selects the records of the newest complete snapshot, so a
+// partially pushed one isn't served until it's completed. Rows without a
+// snapshot_size predate it, and are assumed complete.
+func (tm *MappingTableManager) latestMappingsSQL() string {
+	table := tm.Table()
+	return latestMappingsSQL(fmt.Sprintf("`%s.%s.%s`", table.ProjectID, tm.client.datasetName, table.TableID))
+}
+
+func latestMappingsSQL(name string) string {
+	return fmt.Sprintf("SELECT * FROM %s WHERE created_at = ("+
+		"SELECT MAX(created_at) FROM ("+
+		"SELECT created_at FROM %s GROUP BY created_at "+
+		"HAVING COUNT(*) >= IFNULL(MAX(snapshot_size), 0)))", name, name)
+}
+
 func (tm *MappingTableManager) ListMappings() ([]v1.TestOwnership, error) {
 	now := time.Now()
 	log.Infof("fetching mappings from bigquery")
-	table := tm.client.bigquery.Dataset(tm.client.datasetName).Table(mappingTableName)
+	table := tm.client.bigquery.Dataset(tm.client.datasetName).Table(tm.tableName)
 
 	sql := fmt.Sprintf(`
 		SELECT 
@@ -95,51 +188,414 @@ func (tm *MappingTableManager) ListMappings() ([]v1.TestOwnership, error) {
 	return results, nil
 }
 
+// ListLatestMappings returns the records of the newest snapshot. It queries
+// the table with the same query as the latest view, so it works before the
+// view is created.
+func (tm *MappingTableManager) ListLatestMappings() ([]v1.TestOwnership, error) {
+	now := time.Now()
+	sql := tm.latestMappingsSQL()
+	log.Debugf("query is %q", sql)
+
+	it, err := tm.client.bigquery.Query(sql).Read(tm.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []v1.TestOwnership
+	for {
+		var testOwnership v1.TestOwnership
+		err := it.Next(&testOwnership)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, testOwnership)
+	}
+	log.Infof("fetched %d latest mappings from bigquery in %v", len(results), time.Since(now))
+
+	return results, nil
+}
+
+// PushMappings pushes a snapshot using the manager's push mode.
 func (tm *MappingTableManager) PushMappings(mappings []v1.TestOwnership) error {
+	runID, err := SnapshotRunID(mappings)
+	if err != nil {
+		return err
+	}
+
+	switch tm.pushMode {
+	case PushModeMerge:
+		if runID == "" {
+			return fmt.Errorf("%s push mode requires records tagged with a run ID", PushModeMerge)
+		}
+		return tm.mergeMappings(runID, mappings)
+	case PushModeStream, PushModeLoad, "":
+		if runID != "" {
+			pushed, err := tm.countRun(tm.Table(), runID)
+			if err != nil {
+				return err
+			}
+			switch PlanPush(pushed, len(mappings)) {
+			case PushSkip:
+				log.Infof("run %q was already pushed to %q, skipping", runID, tm.tableName)
+				return nil
+			case PushComplete:
+				log.Warningf("run %q was partially pushed (%d of %d rows), merging in the missing rows",
+					runID, pushed, len(mappings))
+				return tm.mergeMappings(runID, mappings)
+			}
+		}
+		if tm.pushMode == PushModeLoad {
+			return tm.load(tm.Table(), mappings, bigquery.WriteAppend)
+		}
+		return tm.stream(mappings)
+	default:
+		return fmt.Errorf("unknown push mode %q", tm.pushMode)
+	}
+}
+
+// countRun returns the number of rows in a table from a run.
+func (tm *MappingTableManager) countRun(table *bigquery.Table, runID string) (int, error) {
+	sql := fmt.Sprintf("SELECT COUNT(*) AS count FROM `%s.%s.%s` WHERE run_id = @run_id",
+		table.ProjectID, tm.client.datasetName, table.TableID)
+	log.Debugf("query is %q", sql)
+
+	q := tm.client.bigquery.Query(sql)
+	q.Parameters = []bigquery.QueryParameter{{Name: "run_id", Value: runID}}
+	it, err := q.Read(tm.ctx)
+	if err != nil {
+		return 0, err
+	}
+	var row struct {
+		Count int64 `bigquery:"count"`
+	}
+	if err := it.Next(&row); err != nil {
+		return 0, err
+	}
+	return int(row.Count), nil
+}
+
+// runCreatedAt returns the created_at of the rows in the mapping table from a
+// run, which is NULL if there are none.
+func (tm *MappingTableManager) runCreatedAt(runID string) (bigquery.NullDateTime, error) {
+	table := tm.Table()
+	sql := fmt.Sprintf("SELECT MIN(created_at) AS created_at FROM `%s.%s.%s` WHERE run_id = @run_id",
+		table.ProjectID, tm.client.datasetName, table.TableID)
+	log.Debugf("query is %q", sql)
+
+	q := tm.client.bigquery.Query(sql)
+	q.Parameters = []bigquery.QueryParameter{{Name: "run_id", Value: runID}}
+	it, err := q.Read(tm.ctx)
+	if err != nil {
+		return bigquery.NullDateTime{}, err
+	}
+	var row struct {
+		CreatedAt bigquery.NullDateTime `bigquery:"created_at"`
+	}
+	if err := it.Next(&row); err != nil {
+		return bigquery.NullDateTime{}, err
+	}
+	return row.CreatedAt, nil
+}
+
+// stream pushes the mappings with the streaming inserter. Rows that fail to
+// insert are reported individually.
+func (tm *MappingTableManager) stream(mappings []v1.TestOwnership) error {
 	var batchSize = 500
 
-	table := tm.client.bigquery.Dataset(tm.client.datasetName).Table(mappingTableName)
-	inserter := table.Inserter()
+	inserter := tm.Table().Inserter()
+	var failed int
 	for i := 0; i < len(mappings); i += batchSize {
 		end := i + batchSize
 		if end > len(mappings) {
 			end = len(mappings)
 		}
 
-		if err := inserter.Put(tm.ctx, mappings[i:end]); err != nil {
+		err := inserter.Put(tm.ctx, mappings[i:end])
+		var multiErr bigquery.PutMultiError
+		if errors.As(err, &multiErr) {
+			for _, rowErr := range multiErr {
+				m := &mappings[i+rowErr.RowIndex]
+				log.WithFields(log.Fields{
+					"id":    m.ID,
+					"name":  m.Name,
+					"suite": m.Suite,
+				}).WithError(rowErr.Errors).Error("could not insert row")
+			}
+			failed += len(multiErr)
+			log.Infof("added %d rows to mapping bigquery table", end-i-len(multiErr))
+			continue
+		} else if err != nil {
 			return err
 		}
 		log.Infof("added %d rows to mapping bigquery table", end-i)
 	}
 
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rows could not be inserted", failed, len(mappings))
+	}
 	return nil
 }
 
-func (tm *MappingTableManager) PruneMappings() error {
-	now := time.Now()
-	log.Infof("pruning mappings from bigquery")
-	table := tm.client.bigquery.Dataset(tm.client.datasetName).Table(mappingTableName)
+// load appends the mappings to a table with a single load job, from a local
+// NDJSON file.
+func (tm *MappingTableManager) load(table *bigquery.Table, mappings []v1.TestOwnership, disposition bigquery.TableWriteDisposition) error {
+	file, err := os.CreateTemp("", "mappings-*.ndjson")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
 
-	tableLocator := fmt.Sprintf("%s.%s.%s", table.ProjectID, tm.client.datasetName, table.TableID)
+	if err := writeNDJSON(file, mappings); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	source := bigquery.NewReaderSource(file)
+	source.SourceFormat = bigquery.JSON
+	source.Schema = v1.MappingTableSchema
+	loader := table.LoaderFrom(source)
+	loader.WriteDisposition = disposition
 
-	sql := fmt.Sprintf(`DELETE FROM %s WHERE created_at < (SELECT MAX(created_at) FROM %s)`, tableLocator, tableLocator)
+	job, err := loader.Run(tm.ctx)
+	if err != nil {
+		return err
+	}
+	status, err := job.Wait(tm.ctx)
+	if err != nil {
+		return err
+	}
+	if status.Err() != nil {
+		for _, jobErr := range status.Errors {
+			log.WithField("location", jobErr.Location).Error(jobErr.Message)
+		}
+		return fmt.Errorf("load job %s failed: %w", job.ID(), status.Err())
+	}
+	log.Infof("loaded %d rows into %q", len(mappings), table.TableID)
+	return nil
+}
+
+// mergeMappings loads the mappings into a staging table, and MERGEs the rows
+// missing from the mapping table into it. The rows completing a run already
+// partially in the table keep the run's created_at.
+func (tm *MappingTableManager) mergeMappings(runID string, mappings []v1.TestOwnership) error {
+	createdAt, err := tm.runCreatedAt(runID)
+	if err != nil {
+		return err
+	}
+	if createdAt.Valid {
+		mappings = StampCreatedAt(mappings, createdAt.DateTime)
+	}
+
+	dataset := tm.client.bigquery.Dataset(tm.client.datasetName)
+	staging := dataset.Table(fmt.Sprintf("%s_staging_%s", tm.tableName, nonIdentifierRegexp.ReplaceAllString(runID, "_")))
+
+	// Staging tables left behind by failed pushes expire on their own
+	if err := staging.Create(tm.ctx, &bigquery.TableMetadata{
+		Schema:         v1.MappingTableSchema,
+		ExpirationTime: time.Now().Add(24 * time.Hour),
+	}); err != nil {
+		if gbErr, ok := err.(*googleapi.Error); !ok || gbErr.Code != 409 {
+			return err
+		}
+	}
+	defer func() {
+		if err := staging.Delete(tm.ctx); err != nil {
+			log.WithError(err).Warningf("could not delete staging table %q", staging.TableID)
+		}
+	}()
+
+	if err := tm.load(staging, mappings, bigquery.WriteTruncate); err != nil {
+		return err
+	}
+
+	table := tm.Table()
+	sql := fmt.Sprintf("MERGE `%s.%s.%s` T USING `%s.%s.%s` S "+
+		"ON T.run_id = S.run_id AND T.id = S.id AND T.name = S.name AND T.suite = S.suite "+
+		"WHEN NOT MATCHED THEN INSERT ROW",
+		table.ProjectID, tm.client.datasetName, table.TableID,
+		staging.ProjectID, tm.client.datasetName, staging.TableID)
+	log.Debugf("query is %q", sql)
+
+	job, err := tm.client.bigquery.Query(sql).Run(tm.ctx)
+	if err != nil {
+		return err
+	}
+	status, err := job.Wait(tm.ctx)
+	if err != nil {
+		return err
+	}
+	if status.Err() != nil {
+		return fmt.Errorf("merge job %s failed: %w", job.ID(), status.Err())
+	}
+
+	if stats, ok := status.Statistics.Details.(*bigquery.QueryStatistics); ok && stats.NumDMLAffectedRows == 0 {
+		log.Infof("run %q was already pushed to %q", runID, tm.tableName)
+	} else {
+		log.Infof("merged run %q into %q", runID, tm.tableName)
+	}
+	return nil
+}
+
+// writeNDJSON writes one JSON object per mapping, keyed by column name, as
+// expected by a load job.
+func writeNDJSON(w io.Writer, mappings []v1.TestOwnership) error {
+	encoder := json.NewEncoder(w)
+	for i := range mappings {
+		row, _, err := (&bigquery.StructSaver{Struct: &mappings[i], Schema: v1.MappingTableSchema}).Save()
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tm *MappingTableManager) ListSnapshots() ([]Snapshot, error) {
+	table := tm.Table()
+	sql := fmt.Sprintf(`
+		SELECT
+			created_at,
+			ANY_VALUE(run_id) AS run_id,
+			COUNT(*) AS row_count,
+			IFNULL(MAX(snapshot_size), 0) AS expected_rows
+		FROM
+			%s.%s.%s
+		GROUP BY
+			created_at
+		ORDER BY
+			created_at DESC`,
+		table.ProjectID, tm.client.datasetName, table.TableID)
+	log.Debugf("query is %q", sql)
+
+	it, err := tm.client.bigquery.Query(sql).Read(tm.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for {
+		var row struct {
+			CreatedAt    civil.DateTime      `bigquery:"created_at"`
+			RunID        bigquery.NullString `bigquery:"run_id"`
+			Rows         int                 `bigquery:"row_count"`
+			ExpectedRows int                 `bigquery:"expected_rows"`
+		}
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, Snapshot{
+			CreatedAt:    row.CreatedAt,
+			RunID:        row.RunID.StringVal,
+			Rows:         row.Rows,
+			ExpectedRows: row.ExpectedRows,
+		})
+	}
+
+	return snapshots, nil
+}
+
+func (tm *MappingTableManager) PruneMappings(policy RetentionPolicy, dryRun bool) (*PrunePlan, error) {
+	snapshots, err := tm.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	plan, err := policy.Plan(snapshots, time.Now())
+	if err != nil || dryRun || len(plan.Prune) == 0 {
+		return plan, err
+	}
+
+	now := time.Now()
+	log.Infof("pruning %d snapshots from bigquery", len(plan.Prune))
+	table := tm.Table()
+	sql := fmt.Sprintf(`DELETE FROM %s.%s.%s WHERE created_at < @cutoff`, table.ProjectID, tm.client.datasetName, table.TableID)
 	log.Infof("query is %q", sql)
 
 	q := tm.client.bigquery.Query(sql)
-	_, err := q.Read(tm.ctx)
+	q.Parameters = []bigquery.QueryParameter{{Name: "cutoff", Value: plan.Cutoff()}}
+	_, err = q.Read(tm.ctx)
 	log.Infof("pruned mapping table in %+v", time.Since(now))
 	if err != nil && strings.Contains(err.Error(), "streaming") {
 		log.Warningf("got error while trying to prune the table; please wait 90 minutes and try again. You cannot prune after modifying the table.")
 	}
-	return err
+	return plan, err
 }
 
 func (tm *MappingTableManager) Table() *bigquery.Table {
 	dataset := tm.client.bigquery.Dataset(tm.client.datasetName)
-	return dataset.Table(mappingTableName)
+	return dataset.Table(tm.tableName)
+}
+
+// LatestView returns the view of the mapping table's newest snapshot.
+func (tm *MappingTableManager) LatestView() *bigquery.Table {
+	dataset := tm.client.bigquery.Dataset(tm.client.datasetName)
+	return dataset.Table(tm.tableName + LatestViewSuffix)
+}
+
+// RunHistoryTable returns the table recording every mapping run.
+func (tm *MappingTableManager) RunHistoryTable() *bigquery.Table {
+	dataset := tm.client.bigquery.Dataset(tm.client.datasetName)
+	return dataset.Table(tm.tableName + RunHistoryTableSuffix)
+}
+
+func (tm *MappingTableManager) PushRun(run v1.MappingRun) error {
+	table := tm.RunHistoryTable()
+	pushed, err := tm.countRun(table, run.RunID)
+	if err != nil {
+		return err
+	}
+	if pushed > 0 {
+		log.Infof("run %q was already recorded in %q, skipping", run.RunID, table.TableID)
+		return nil
+	}
+
+	if err := table.Inserter().Put(tm.ctx, &run); err != nil {
+		return err
+	}
+	log.Infof("recorded run %q in %q", run.RunID, table.TableID)
+	return nil
+}
+
+func (tm *MappingTableManager) ListRuns() ([]v1.MappingRun, error) {
+	table := tm.RunHistoryTable()
+	sql := fmt.Sprintf("SELECT * FROM `%s.%s.%s` ORDER BY created_at DESC",
+		table.ProjectID, tm.client.datasetName, table.TableID)
+	log.Debugf("query is %q", sql)
+
+	it, err := tm.client.bigquery.Query(sql).Read(tm.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []v1.MappingRun
+	for {
+		var run v1.MappingRun
+		err := it.Next(&run)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
 }
 
-func schemasEqual(a, b bigquery.Schema) bool {
+// SchemasEqual reports whether two schemas have the same fields, in the same
+// order, with the same types and modes.
+func SchemasEqual(a, b bigquery.Schema) bool {
 	if len(a) != len(b) {
 		return false
 	}
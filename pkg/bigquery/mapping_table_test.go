@@ -0,0 +1,126 @@
+package bigquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/civil"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+func TestSnapshotRunID(t *testing.T) {
+	tests := []struct {
+		name      string
+		mappings  []v1.TestOwnership
+		want      string
+		wantError bool
+	}{
+		{
+			name: "empty snapshot",
+		},
+		{
+			name:     "untagged snapshot",
+			mappings: []v1.TestOwnership{{ID: "1"}, {ID: "2"}},
+		},
+		{
+			name:     "tagged snapshot",
+			mappings: []v1.TestOwnership{{ID: "1", RunID: "run"}, {ID: "2", RunID: "run"}},
+			want:     "run",
+		},
+		{
+			name:      "mixed runs",
+			mappings:  []v1.TestOwnership{{ID: "1", RunID: "run"}, {ID: "2", RunID: "other"}},
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SnapshotRunID(tt.mappings)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("SnapshotRunID() error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("SnapshotRunID() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanPush(t *testing.T) {
+	tests := []struct {
+		name   string
+		pushed int
+		want   PushAction
+	}{
+		{name: "new run", pushed: 0, want: PushAll},
+		{name: "partially pushed run", pushed: 1, want: PushComplete},
+		{name: "pushed run", pushed: 3, want: PushSkip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlanPush(tt.pushed, 3); got != tt.want {
+				t.Errorf("PlanPush(%d, 3) = %v, want %v", tt.pushed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatestMappingsSQL(t *testing.T) {
+	want := "SELECT * FROM `p.d.t` WHERE created_at = (SELECT MAX(created_at) FROM (" +
+		"SELECT created_at FROM `p.d.t` GROUP BY created_at " +
+		"HAVING COUNT(*) >= IFNULL(MAX(snapshot_size), 0)))"
+	if got := latestMappingsSQL("`p.d.t`"); got != want {
+		t.Errorf("latestMappingsSQL() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	mappings := []v1.TestOwnership{
+		{
+			ID:           "1",
+			Name:         "a",
+			Component:    "A",
+			Capabilities: []string{"x", "y"},
+			CreatedAt:    civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 1}, Time: civil.Time{Hour: 12, Nanosecond: 1500}},
+			RunID:        "run",
+		},
+		{ID: "2", Name: "b", Component: "B", RunID: "run"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeNDJSON(&buf, mappings); err != nil {
+		t.Fatalf("writeNDJSON() returned unexpected err: %+v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(mappings) {
+		t.Fatalf("writeNDJSON() wrote %d lines, want %d", len(lines), len(mappings))
+	}
+	var row map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &row); err != nil {
+		t.Fatalf("writeNDJSON() wrote invalid JSON: %v", err)
+	}
+	for column, want := range map[string]interface{}{
+		"id":           "1",
+		"component":    "A",
+		"capabilities": []interface{}{"x", "y"},
+		"created_at":   "2023-05-01 12:00:00.000002",
+		"run_id":       "run",
+	} {
+		if got, _ := json.Marshal(row[column]); !bytes.Equal(got, mustMarshal(t, want)) {
+			t.Errorf("writeNDJSON() column %s = %s, want %s", column, got, mustMarshal(t, want))
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
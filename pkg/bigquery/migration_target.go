@@ -0,0 +1,149 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+)
+
+// tableMigrationTarget applies migrations to a BigQuery table, recording
+// them in a metadata table next to it. The table is created with the given
+// partitioning and clustering, if any.
+type tableMigrationTarget struct {
+	ctx    context.Context
+	client *Client
+	table  *bigquery.Table
+
+	timePartitioning *bigquery.TimePartitioning
+	clustering       *bigquery.Clustering
+}
+
+func (t *tableMigrationTarget) Name() string {
+	return t.table.TableID
+}
+
+func (t *tableMigrationTarget) Schema() (bigquery.Schema, bool, error) {
+	md, err := t.table.Metadata(t.ctx)
+	if gbErr, ok := err.(*googleapi.Error); ok && gbErr.Code == 404 {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return md.Schema, true, nil
+}
+
+func (t *tableMigrationTarget) CreateTable(schema bigquery.Schema) error {
+	if err := t.table.Create(t.ctx, &bigquery.TableMetadata{
+		Schema:           schema,
+		TimePartitioning: t.timePartitioning,
+		Clustering:       t.clustering,
+	}); err != nil {
+		return err
+	}
+	log.Infof("table created %q", t.table.TableID)
+	return nil
+}
+
+func (t *tableMigrationTarget) UpdateSchema(schema bigquery.Schema) error {
+	if _, err := t.table.Update(t.ctx, bigquery.TableMetadataToUpdate{Schema: schema}, ""); err != nil {
+		log.WithError(err).Errorf("failed to update table schema for %q", t.table.TableID)
+		return err
+	}
+	log.Infof("table schema updated %q", t.table.TableID)
+	return nil
+}
+
+func (t *tableMigrationTarget) RenameColumn(from, to string) error {
+	return t.exec(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN IF EXISTS `%s` TO `%s`", t.qualifiedName(t.table), from, to))
+}
+
+func (t *tableMigrationTarget) DropColumn(name string) error {
+	return t.exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS `%s`", t.qualifiedName(t.table), name))
+}
+
+func (t *tableMigrationTarget) Backfill(backfill Backfill) error {
+	err := t.exec(fmt.Sprintf("UPDATE %s SET `%s` = (%s) WHERE `%s` IS NULL",
+		t.qualifiedName(t.table), backfill.Column, backfill.SQL, backfill.Column))
+	if err != nil && strings.Contains(err.Error(), "streaming") {
+		log.Warningf("got error while trying to backfill the table; please wait 90 minutes and try again. You cannot update rows after streaming to the table.")
+	}
+	return err
+}
+
+func (t *tableMigrationTarget) AppliedMigrations() ([]MigrationRecord, error) {
+	migrations := t.migrationsTable()
+	if _, err := migrations.Metadata(t.ctx); err != nil {
+		if gbErr, ok := err.(*googleapi.Error); !ok || gbErr.Code != 404 {
+			return nil, err
+		}
+		log.Infof("creating migrations table %q", migrations.TableID)
+		if err := migrations.Create(t.ctx, &bigquery.TableMetadata{Schema: MigrationsTableSchema}); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	sql := fmt.Sprintf("SELECT * FROM %s ORDER BY migration_id", t.qualifiedName(migrations))
+	log.Debugf("query is %q", sql)
+	it, err := t.client.bigquery.Query(sql).Read(t.ctx)
+	if err != nil {
+		return nil, err
+	}
+	var records []MigrationRecord
+	for {
+		var record MigrationRecord
+		err := it.Next(&record)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// RecordMigration inserts with DML rather than the streaming inserter, so the
+// record is visible to the next run right away.
+func (t *tableMigrationTarget) RecordMigration(record MigrationRecord) error {
+	return t.exec(fmt.Sprintf("INSERT INTO %s (migration_id, api_version, description, applied_at) "+
+		"VALUES (@migration_id, @api_version, @description, @applied_at)", t.qualifiedName(t.migrationsTable())),
+		bigquery.QueryParameter{Name: "migration_id", Value: record.MigrationID},
+		bigquery.QueryParameter{Name: "api_version", Value: record.APIVersion},
+		bigquery.QueryParameter{Name: "description", Value: record.Description},
+		bigquery.QueryParameter{Name: "applied_at", Value: record.AppliedAt},
+	)
+}
+
+func (t *tableMigrationTarget) migrationsTable() *bigquery.Table {
+	return t.client.bigquery.Dataset(t.client.datasetName).Table(t.table.TableID + MigrationsTableSuffix)
+}
+
+func (t *tableMigrationTarget) qualifiedName(table *bigquery.Table) string {
+	return fmt.Sprintf("`%s.%s.%s`", table.ProjectID, t.client.datasetName, table.TableID)
+}
+
+// exec runs a DDL or DML statement and waits for it to finish.
+func (t *tableMigrationTarget) exec(sql string, params ...bigquery.QueryParameter) error {
+	log.Debugf("query is %q", sql)
+	q := t.client.bigquery.Query(sql)
+	q.Parameters = params
+	job, err := q.Run(t.ctx)
+	if err != nil {
+		return err
+	}
+	status, err := job.Wait(t.ctx)
+	if err != nil {
+		return err
+	}
+	if status.Err() != nil {
+		return fmt.Errorf("query job %s failed: %w", job.ID(), status.Err())
+	}
+	return nil
+}
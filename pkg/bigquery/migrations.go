@@ -0,0 +1,482 @@
+package bigquery
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	log "github.com/sirupsen/logrus"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+// MigrationsTableSuffix is appended to a table's name to name the metadata
+// table recording the migrations applied to it.
+const MigrationsTableSuffix = "_migrations"
+
+// RunHistoryTableSuffix is appended to the mapping table's name to name the
+// run-history table.
+const RunHistoryTableSuffix = "_runs"
+
+// Migration is one versioned change to a table. Migrations are applied in
+// order, and each is recorded once applied, so an applied migration must
+// never be changed; add a new one instead.
+//
+// Every step is idempotent: adding a column that exists with the same type,
+// or dropping or renaming one that's already gone, does nothing. That lets a
+// table created before migrations were recorded be brought up to date, and a
+// failed migration be retried.
+type Migration struct {
+	ID string
+	// APIVersion is the TestOwnership API version the table conforms to once
+	// the migration is applied.
+	APIVersion  string
+	Description string
+
+	// AddColumns are added at the end of the table, or of a RECORD column.
+	AddColumns []ColumnAddition
+
+	// Backfills fill in a column on the rows where it's NULL, such as the
+	// rows that existed before it was added. They run after the columns are
+	// added.
+	Backfills []Backfill
+
+	// RenameColumns and DropColumns change top-level columns. They're
+	// destructive, as readers of the old columns break, so migrations
+	// containing them are only applied when explicitly allowed.
+	RenameColumns []ColumnRename
+	DropColumns   []string
+}
+
+// ColumnAddition adds a field to a table.
+type ColumnAddition struct {
+	// Parent is the dotted path of the RECORD column the field is added to,
+	// or empty for a top-level column.
+	Parent string
+	Field  *bigquery.FieldSchema
+}
+
+// ColumnRename renames a top-level column.
+type ColumnRename struct {
+	From string
+	To   string
+}
+
+// Backfill computes a column for existing rows. SQL and Apply must agree:
+// SQL is used in BigQuery, and Apply in the file-backed store.
+type Backfill struct {
+	Column string
+
+	// SQL is a BigQuery expression computing the column from the row's
+	// other columns.
+	SQL string
+
+	// Apply sets the column on a row.
+	Apply func(*v1.TestOwnership)
+}
+
+// Destructive reports whether the migration renames or drops columns.
+func (m *Migration) Destructive() bool {
+	return len(m.RenameColumns) > 0 || len(m.DropColumns) > 0
+}
+
+// MigrationRecord is a row of a migrations metadata table.
+type MigrationRecord struct {
+	MigrationID string         `bigquery:"migration_id"`
+	APIVersion  string         `bigquery:"api_version"`
+	Description string         `bigquery:"description"`
+	AppliedAt   civil.DateTime `bigquery:"applied_at"`
+}
+
+var MigrationsTableSchema = bigquery.Schema{
+	{Name: "migration_id", Type: bigquery.StringFieldType, Required: true},
+	{Name: "api_version", Type: bigquery.StringFieldType},
+	{Name: "description", Type: bigquery.StringFieldType},
+	{Name: "applied_at", Type: bigquery.DateTimeFieldType},
+}
+
+// MigrationTarget is a table that migrations can be applied to, in BigQuery
+// or the file-backed store.
+type MigrationTarget interface {
+	// Name is the table's name, for logging.
+	Name() string
+
+	// Schema returns the table's schema, or false if it doesn't exist.
+	Schema() (bigquery.Schema, bool, error)
+	CreateTable(schema bigquery.Schema) error
+	UpdateSchema(schema bigquery.Schema) error
+	RenameColumn(from, to string) error
+	DropColumn(name string) error
+	Backfill(backfill Backfill) error
+
+	// AppliedMigrations lists the migrations recorded in the metadata
+	// table, creating it if needed, and RecordMigration adds one.
+	AppliedMigrations() ([]MigrationRecord, error)
+	RecordMigration(record MigrationRecord) error
+}
+
+// MappingTableMigrations evolve the mapping table to v1.MappingTableSchema.
+var MappingTableMigrations = []Migration{
+	{
+		ID:          "0001-initial",
+		APIVersion:  "v1",
+		Description: "Create the mapping table",
+		AddColumns: columns(
+			&bigquery.FieldSchema{Name: "kind", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "apiVersion", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "id", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "name", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "suite", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "product", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "component", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "jira_component", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "capabilities", Type: bigquery.StringFieldType, Repeated: true},
+			&bigquery.FieldSchema{Name: "staff_approved_obsolete", Type: bigquery.BooleanFieldType},
+			&bigquery.FieldSchema{Name: "priority", Type: bigquery.IntegerFieldType},
+			&bigquery.FieldSchema{Name: "created_at", Type: bigquery.DateTimeFieldType},
+		),
+	},
+	{
+		ID:          "0002-fingerprints",
+		APIVersion:  "v1",
+		Description: "Add test and registry fingerprints for incremental mapping",
+		AddColumns: columns(
+			&bigquery.FieldSchema{Name: "test_fingerprint", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "registry_fingerprint", Type: bigquery.StringFieldType},
+		),
+	},
+	{
+		ID:          "0003-run-id",
+		APIVersion:  "v1",
+		Description: "Add run IDs, and give older snapshots one derived from their creation time",
+		AddColumns: columns(
+			&bigquery.FieldSchema{Name: "run_id", Type: bigquery.StringFieldType},
+		),
+		Backfills: []Backfill{
+			{
+				Column: "run_id",
+				SQL:    "CONCAT('legacy-', FORMAT_DATETIME('%Y%m%dT%H%M%E6S', created_at))",
+				Apply: func(m *v1.TestOwnership) {
+					t := m.CreatedAt
+					m.RunID = fmt.Sprintf("legacy-%04d%02d%02dT%02d%02d%02d.%06d", t.Date.Year, t.Date.Month, t.Date.Day,
+						t.Time.Hour, t.Time.Minute, t.Time.Second, t.Time.Nanosecond/1000)
+				},
+			},
+		},
+	},
+	{
+		ID:          "0004-snapshot-size",
+		APIVersion:  "v1",
+		Description: "Add the snapshot size, to detect incomplete snapshots",
+		AddColumns: columns(
+			&bigquery.FieldSchema{Name: "snapshot_size", Type: bigquery.IntegerFieldType},
+		),
+	},
+	{
+		ID:          "0005-provenance",
+		APIVersion:  "v1",
+		Description: "Add the tool version and commit, test source and input test count",
+		AddColumns: columns(
+			&bigquery.FieldSchema{Name: "tool_version", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "tool_commit", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "test_source", Type: bigquery.StringFieldType},
+			&bigquery.FieldSchema{Name: "input_test_count", Type: bigquery.IntegerFieldType},
+		),
+	},
+}
+
+// RunHistoryTableMigrations evolve the run-history table to
+// v1.RunHistoryTableSchema.
+var RunHistoryTableMigrations = []Migration{
+	{
+		ID:          "0001-initial",
+		APIVersion:  "v1",
+		Description: "Create the run-history table",
+		AddColumns:  columns(v1.RunHistoryTableSchema...),
+	},
+}
+
+func columns(fields ...*bigquery.FieldSchema) []ColumnAddition {
+	additions := make([]ColumnAddition, 0, len(fields))
+	for _, field := range fields {
+		additions = append(additions, ColumnAddition{Field: field})
+	}
+	return additions
+}
+
+// MigratedSchema returns the schema a table has after applying the
+// migrations to an empty table.
+func MigratedSchema(migrations []Migration) (bigquery.Schema, error) {
+	var schema bigquery.Schema
+	for i := range migrations {
+		var err error
+		if schema, err = applyToSchema(schema, &migrations[i]); err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+func applyToSchema(schema bigquery.Schema, m *Migration) (bigquery.Schema, error) {
+	var err error
+	for _, addition := range m.AddColumns {
+		if schema, _, err = addColumn(schema, addition); err != nil {
+			return nil, fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+	}
+	for _, rename := range m.RenameColumns {
+		schema = renameColumn(schema, rename)
+	}
+	for _, name := range m.DropColumns {
+		schema = dropColumn(schema, name)
+	}
+	return schema, nil
+}
+
+// RunMigrations applies the migrations that haven't been applied to the
+// target yet, in order. A table that doesn't exist yet is created with the
+// final schema. Destructive migrations are refused unless allowDestructive
+// is set. Finally, the table's schema is checked against want.
+func RunMigrations(target MigrationTarget, migrations []Migration, want bigquery.Schema, allowDestructive bool) error {
+	applied, err := target.AppliedMigrations()
+	if err != nil {
+		return fmt.Errorf("could not list applied migrations: %w", err)
+	}
+	known := make(map[string]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.ID] = true
+	}
+	done := make(map[string]bool, len(applied))
+	for _, record := range applied {
+		if !known[record.MigrationID] {
+			return fmt.Errorf("table has migration %q (API version %s) applied, which this version doesn't know; "+
+				"it was migrated by a newer version", record.MigrationID, record.APIVersion)
+		}
+		done[record.MigrationID] = true
+	}
+
+	var pending []*Migration
+	var destructive []string
+	for i := range migrations {
+		if !done[migrations[i].ID] {
+			pending = append(pending, &migrations[i])
+			if migrations[i].Destructive() {
+				destructive = append(destructive, migrations[i].ID)
+			}
+		}
+	}
+	if len(pending) == 0 {
+		log.Infof("table schema is up-to-date %q", target.Name())
+		return checkSchema(target, want)
+	}
+
+	schema, exists, err := target.Schema()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		// There's no data to migrate
+		final, err := MigratedSchema(migrations)
+		if err != nil {
+			return err
+		}
+		log.Infof("table doesn't exist, creating table %q", target.Name())
+		if err := target.CreateTable(final); err != nil {
+			return err
+		}
+	} else if len(destructive) > 0 && !allowDestructive {
+		return fmt.Errorf("migrations %s rename or drop columns; allow destructive migrations to apply them",
+			strings.Join(destructive, ", "))
+	}
+
+	for _, m := range pending {
+		if exists {
+			log.WithField("migration", m.ID).Infof("applying migration to %q: %s", target.Name(), m.Description)
+			if schema, err = applyMigration(target, schema, m); err != nil {
+				return fmt.Errorf("migration %s failed: %w", m.ID, err)
+			}
+		}
+		if err := target.RecordMigration(MigrationRecord{
+			MigrationID: m.ID,
+			APIVersion:  m.APIVersion,
+			Description: m.Description,
+			AppliedAt:   civil.DateTimeOf(time.Now()),
+		}); err != nil {
+			return fmt.Errorf("could not record migration %s: %w", m.ID, err)
+		}
+	}
+	log.Infof("table %q migrated to API version %s", target.Name(), pending[len(pending)-1].APIVersion)
+
+	return checkSchema(target, want)
+}
+
+func applyMigration(target MigrationTarget, schema bigquery.Schema, m *Migration) (bigquery.Schema, error) {
+	updated := schema
+	var changed bool
+	for _, addition := range m.AddColumns {
+		var added bool
+		var err error
+		if updated, added, err = addColumn(updated, addition); err != nil {
+			return nil, err
+		}
+		changed = changed || added
+	}
+	if changed {
+		if err := target.UpdateSchema(updated); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, backfill := range m.Backfills {
+		if err := target.Backfill(backfill); err != nil {
+			return nil, fmt.Errorf("could not backfill %s: %w", backfill.Column, err)
+		}
+	}
+
+	for _, rename := range m.RenameColumns {
+		if findField(updated, rename.From) == nil {
+			continue
+		}
+		if err := target.RenameColumn(rename.From, rename.To); err != nil {
+			return nil, err
+		}
+		updated = renameColumn(updated, rename)
+	}
+	for _, name := range m.DropColumns {
+		if findField(updated, name) == nil {
+			continue
+		}
+		if err := target.DropColumn(name); err != nil {
+			return nil, err
+		}
+		updated = dropColumn(updated, name)
+	}
+
+	return updated, nil
+}
+
+// checkSchema verifies the table has every column in want, with the same
+// type and mode, in any order. Extra columns are reported but allowed.
+func checkSchema(target MigrationTarget, want bigquery.Schema) error {
+	schema, _, err := target.Schema()
+	if err != nil {
+		return err
+	}
+	problems, extra := compareSchemas(schema, want, "")
+	for _, name := range extra {
+		log.Warningf("table %q has column %q, which isn't in the schema", target.Name(), name)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("table %q schema doesn't match after migrating: %s", target.Name(), strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func compareSchemas(got, want bigquery.Schema, prefix string) (problems, extra []string) {
+	for _, w := range want {
+		g := findField(got, w.Name)
+		switch {
+		case g == nil:
+			problems = append(problems, fmt.Sprintf("column %s%s is missing", prefix, w.Name))
+		case !sameType(g, w):
+			problems = append(problems, fmt.Sprintf("column %s%s is %s, want %s", prefix, w.Name, describeField(g), describeField(w)))
+		case w.Type == bigquery.RecordFieldType:
+			p, e := compareSchemas(g.Schema, w.Schema, prefix+w.Name+".")
+			problems = append(problems, p...)
+			extra = append(extra, e...)
+		}
+	}
+	for _, g := range got {
+		if findField(want, g.Name) == nil {
+			extra = append(extra, prefix+g.Name)
+		}
+	}
+	return problems, extra
+}
+
+// addColumn returns the schema with the column added, and whether it was
+// missing. Adding a column that exists with a different type or mode is an
+// error.
+func addColumn(schema bigquery.Schema, addition ColumnAddition) (bigquery.Schema, bool, error) {
+	updated := copySchema(schema)
+	fields := &updated
+	if addition.Parent != "" {
+		for _, name := range strings.Split(addition.Parent, ".") {
+			parent := findField(*fields, name)
+			if parent == nil || parent.Type != bigquery.RecordFieldType {
+				return nil, false, fmt.Errorf("cannot add %s to %s, which isn't a RECORD column", addition.Field.Name, addition.Parent)
+			}
+			fields = &parent.Schema
+		}
+	}
+
+	if existing := findField(*fields, addition.Field.Name); existing != nil {
+		if !sameType(existing, addition.Field) {
+			return nil, false, fmt.Errorf("column %s exists as %s, incompatible with %s",
+				addition.Field.Name, describeField(existing), describeField(addition.Field))
+		}
+		return schema, false, nil
+	}
+	if addition.Field.Required {
+		return nil, false, fmt.Errorf("cannot add REQUIRED column %s to an existing table", addition.Field.Name)
+	}
+	field := *addition.Field
+	*fields = append(*fields, &field)
+	return updated, true, nil
+}
+
+func renameColumn(schema bigquery.Schema, rename ColumnRename) bigquery.Schema {
+	updated := copySchema(schema)
+	if field := findField(updated, rename.From); field != nil {
+		field.Name = rename.To
+	}
+	return updated
+}
+
+func dropColumn(schema bigquery.Schema, name string) bigquery.Schema {
+	var updated bigquery.Schema
+	for _, field := range copySchema(schema) {
+		if !strings.EqualFold(field.Name, name) {
+			updated = append(updated, field)
+		}
+	}
+	return updated
+}
+
+// copySchema copies a schema deeply enough to modify its fields.
+func copySchema(schema bigquery.Schema) bigquery.Schema {
+	copied := make(bigquery.Schema, 0, len(schema))
+	for _, field := range schema {
+		f := *field
+		f.Schema = copySchema(field.Schema)
+		copied = append(copied, &f)
+	}
+	return copied
+}
+
+// findField finds a field by name, case-insensitively like BigQuery.
+func findField(schema bigquery.Schema, name string) *bigquery.FieldSchema {
+	for _, field := range schema {
+		if strings.EqualFold(field.Name, name) {
+			return field
+		}
+	}
+	return nil
+}
+
+func sameType(a, b *bigquery.FieldSchema) bool {
+	return a.Type == b.Type && a.Repeated == b.Repeated && a.Required == b.Required
+}
+
+func describeField(f *bigquery.FieldSchema) string {
+	switch {
+	case f.Repeated:
+		return "REPEATED " + string(f.Type)
+	case f.Required:
+		return "REQUIRED " + string(f.Type)
+	default:
+		return string(f.Type)
+	}
+}
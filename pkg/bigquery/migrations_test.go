@@ -0,0 +1,141 @@
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+func TestMappingTableMigrations(t *testing.T) {
+	schema, err := MigratedSchema(MappingTableMigrations)
+	if err != nil {
+		t.Fatalf("MigratedSchema() returned unexpected err: %+v", err)
+	}
+	if !SchemasEqual(schema, v1.MappingTableSchema) {
+		t.Errorf("MappingTableMigrations create schema %+v, want v1.MappingTableSchema %+v", schema, v1.MappingTableSchema)
+	}
+
+	if last := MappingTableMigrations[len(MappingTableMigrations)-1]; last.APIVersion != v1.APIVersion {
+		t.Errorf("last migration %s has API version %s, want %s", last.ID, last.APIVersion, v1.APIVersion)
+	}
+	for i := 1; i < len(MappingTableMigrations); i++ {
+		if MappingTableMigrations[i].ID <= MappingTableMigrations[i-1].ID {
+			t.Errorf("migration %s is out of order", MappingTableMigrations[i].ID)
+		}
+	}
+}
+
+func TestRunHistoryTableMigrations(t *testing.T) {
+	schema, err := MigratedSchema(RunHistoryTableMigrations)
+	if err != nil {
+		t.Fatalf("MigratedSchema() returned unexpected err: %+v", err)
+	}
+	if problems, extra := compareSchemas(schema, v1.RunHistoryTableSchema, ""); len(problems) > 0 || len(extra) > 0 {
+		t.Errorf("RunHistoryTableMigrations create a schema differing from v1.RunHistoryTableSchema: %v, extra %v", problems, extra)
+	}
+}
+
+func TestRunIDBackfill(t *testing.T) {
+	m := v1.TestOwnership{CreatedAt: civil.DateTime{
+		Date: civil.Date{Year: 2023, Month: 5, Day: 1},
+		Time: civil.Time{Hour: 12, Minute: 3, Second: 4, Nanosecond: 500000000},
+	}}
+	MappingTableMigrations[2].Backfills[0].Apply(&m)
+	if want := "legacy-20230501T120304.500000"; m.RunID != want {
+		t.Errorf("run_id backfill = %q, want %q", m.RunID, want)
+	}
+}
+
+func TestAddColumn(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "name", Type: bigquery.StringFieldType},
+		{Name: "details", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "size", Type: bigquery.IntegerFieldType},
+		}},
+	}
+
+	tests := []struct {
+		name        string
+		addition    ColumnAddition
+		wantChanged bool
+		wantError   bool
+	}{
+		{
+			name:        "new column",
+			addition:    ColumnAddition{Field: &bigquery.FieldSchema{Name: "owner", Type: bigquery.StringFieldType}},
+			wantChanged: true,
+		},
+		{
+			name:     "existing column",
+			addition: ColumnAddition{Field: &bigquery.FieldSchema{Name: "Name", Type: bigquery.StringFieldType}},
+		},
+		{
+			name:      "incompatible column",
+			addition:  ColumnAddition{Field: &bigquery.FieldSchema{Name: "name", Type: bigquery.StringFieldType, Repeated: true}},
+			wantError: true,
+		},
+		{
+			name:      "required column",
+			addition:  ColumnAddition{Field: &bigquery.FieldSchema{Name: "owner", Type: bigquery.StringFieldType, Required: true}},
+			wantError: true,
+		},
+		{
+			name:        "nested column",
+			addition:    ColumnAddition{Parent: "details", Field: &bigquery.FieldSchema{Name: "count", Type: bigquery.IntegerFieldType}},
+			wantChanged: true,
+		},
+		{
+			name:      "parent isn't a record",
+			addition:  ColumnAddition{Parent: "name", Field: &bigquery.FieldSchema{Name: "count", Type: bigquery.IntegerFieldType}},
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, changed, err := addColumn(schema, tt.addition)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("addColumn() error = %v, wantError %v", err, tt.wantError)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("addColumn() changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if err != nil {
+				return
+			}
+
+			fields := updated
+			if tt.addition.Parent != "" {
+				fields = findField(updated, tt.addition.Parent).Schema
+			}
+			if findField(fields, tt.addition.Field.Name) == nil {
+				t.Errorf("addColumn() returned schema without %s", tt.addition.Field.Name)
+			}
+			if len(schema) != 2 || len(schema[1].Schema) != 1 {
+				t.Errorf("addColumn() modified the original schema")
+			}
+		})
+	}
+}
+
+func TestCompareSchemas(t *testing.T) {
+	want := bigquery.Schema{
+		{Name: "a", Type: bigquery.StringFieldType},
+		{Name: "b", Type: bigquery.IntegerFieldType},
+	}
+	got := bigquery.Schema{
+		{Name: "c", Type: bigquery.StringFieldType},
+		{Name: "b", Type: bigquery.StringFieldType},
+		{Name: "a", Type: bigquery.StringFieldType},
+	}
+
+	problems, extra := compareSchemas(got, want, "")
+	if len(problems) != 1 {
+		t.Errorf("compareSchemas() problems = %v, want only b's type", problems)
+	}
+	if len(extra) != 1 || extra[0] != "c" {
+		t.Errorf("compareSchemas() extra = %v, want [c]", extra)
+	}
+}
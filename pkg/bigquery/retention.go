@@ -0,0 +1,114 @@
+package bigquery
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// DefaultMinSizeRatio is how small the newest snapshot may be, relative to
+// the previous one, before pruning is refused.
+const DefaultMinSizeRatio = 0.8
+
+// Snapshot summarizes the rows of one mapping snapshot, i.e. the records
+// sharing a creation time.
+type Snapshot struct {
+	CreatedAt civil.DateTime `json:"created_at"`
+	RunID     string         `json:"run_id,omitempty"`
+	Rows      int            `json:"rows"`
+
+	// ExpectedRows is the number of rows the snapshot was pushed with, or 0
+	// for snapshots from before it was recorded.
+	ExpectedRows int `json:"expected_rows,omitempty"`
+}
+
+// Complete reports whether every row of the snapshot is in the table, as far
+// as is known.
+func (s *Snapshot) Complete() bool {
+	return s.ExpectedRows == 0 || s.Rows >= s.ExpectedRows
+}
+
+// RetentionPolicy decides which snapshots PruneMappings keeps. A snapshot is
+// kept if it's one of the KeepLast newest, or was created within KeepWithin.
+// With neither set, only the newest snapshot is kept, which is always kept
+// regardless.
+type RetentionPolicy struct {
+	KeepLast   int
+	KeepWithin time.Duration
+
+	// MinSizeRatio refuses pruning when the newest snapshot has fewer rows
+	// than this fraction of the previous one, which suggests a truncated
+	// push. Pruning is also refused when the newest snapshot is incomplete.
+	// Force skips both checks.
+	MinSizeRatio float64
+	Force        bool
+}
+
+// PrunePlan lists the snapshots PruneMappings keeps and deletes, newest first.
+type PrunePlan struct {
+	Keep  []Snapshot `json:"keep"`
+	Prune []Snapshot `json:"prune"`
+}
+
+// Cutoff returns the creation time of the oldest kept snapshot; every row
+// created before it is pruned.
+func (p *PrunePlan) Cutoff() civil.DateTime {
+	if len(p.Keep) == 0 {
+		return civil.DateTime{}
+	}
+	return p.Keep[len(p.Keep)-1].CreatedAt
+}
+
+// PrunedRows returns the number of rows the plan deletes.
+func (p *PrunePlan) PrunedRows() int {
+	var rows int
+	for _, s := range p.Prune {
+		rows += s.Rows
+	}
+	return rows
+}
+
+// Plan decides which snapshots to keep and which to prune, as of now. It
+// returns an error if the newest snapshot fails the safety checks and any
+// snapshot would be pruned.
+func (p RetentionPolicy) Plan(snapshots []Snapshot, now time.Time) (*PrunePlan, error) {
+	sorted := append([]Snapshot{}, snapshots...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	keepLast := p.KeepLast
+	if keepLast < 1 && p.KeepWithin <= 0 {
+		keepLast = 1
+	}
+	since := civil.DateTimeOf(now.Add(-p.KeepWithin))
+
+	// Both rules keep the newest snapshots, so the kept ones are always
+	// newer than the pruned ones.
+	plan := &PrunePlan{}
+	for i, s := range sorted {
+		if i == 0 || i < keepLast || (p.KeepWithin > 0 && !s.CreatedAt.Before(since)) {
+			plan.Keep = append(plan.Keep, s)
+		} else {
+			plan.Prune = append(plan.Prune, s)
+		}
+	}
+
+	if len(plan.Prune) == 0 || p.Force {
+		return plan, nil
+	}
+
+	newest := &sorted[0]
+	if !newest.Complete() {
+		return plan, fmt.Errorf("newest snapshot from %s is incomplete, with %d of %d rows",
+			newest.CreatedAt, newest.Rows, newest.ExpectedRows)
+	}
+	if previous := &sorted[1]; float64(newest.Rows) < p.MinSizeRatio*float64(previous.Rows) {
+		return plan, fmt.Errorf("newest snapshot from %s has %d rows, less than %.0f%% of the %d rows of the previous one",
+			newest.CreatedAt, newest.Rows, p.MinSizeRatio*100, previous.Rows)
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,124 @@
+package bigquery
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+func TestRetentionPolicyPlan(t *testing.T) {
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) civil.DateTime {
+		return civil.DateTimeOf(now.Add(-time.Duration(days) * 24 * time.Hour))
+	}
+	snapshots := []Snapshot{
+		{CreatedAt: daysAgo(3), Rows: 100},
+		{CreatedAt: daysAgo(0), Rows: 100, ExpectedRows: 100},
+		{CreatedAt: daysAgo(1), Rows: 100},
+		{CreatedAt: daysAgo(10), Rows: 90},
+	}
+
+	tests := []struct {
+		name      string
+		policy    RetentionPolicy
+		snapshots []Snapshot
+		wantKeep  int
+		wantRows  int
+		wantError bool
+	}{
+		{
+			name:      "keeps only the newest by default",
+			snapshots: snapshots,
+			wantKeep:  1,
+			wantRows:  290,
+		},
+		{
+			name:      "keep last",
+			policy:    RetentionPolicy{KeepLast: 2},
+			snapshots: snapshots,
+			wantKeep:  2,
+			wantRows:  190,
+		},
+		{
+			name:      "keep within",
+			policy:    RetentionPolicy{KeepWithin: 72 * time.Hour},
+			snapshots: snapshots,
+			wantKeep:  3,
+			wantRows:  90,
+		},
+		{
+			name:      "keep last or within",
+			policy:    RetentionPolicy{KeepLast: 3, KeepWithin: 36 * time.Hour},
+			snapshots: snapshots,
+			wantKeep:  3,
+			wantRows:  90,
+		},
+		{
+			name:      "keeps everything",
+			policy:    RetentionPolicy{KeepLast: 10},
+			snapshots: snapshots,
+			wantKeep:  4,
+		},
+		{
+			name:   "refuses when the newest is much smaller",
+			policy: RetentionPolicy{MinSizeRatio: DefaultMinSizeRatio},
+			snapshots: []Snapshot{
+				{CreatedAt: daysAgo(0), Rows: 10},
+				{CreatedAt: daysAgo(1), Rows: 100},
+			},
+			wantKeep:  1,
+			wantRows:  100,
+			wantError: true,
+		},
+		{
+			name:   "refuses when the newest is incomplete",
+			policy: RetentionPolicy{MinSizeRatio: DefaultMinSizeRatio},
+			snapshots: []Snapshot{
+				{CreatedAt: daysAgo(0), Rows: 99, ExpectedRows: 100},
+				{CreatedAt: daysAgo(1), Rows: 99},
+			},
+			wantKeep:  1,
+			wantRows:  99,
+			wantError: true,
+		},
+		{
+			name:   "force skips the safety checks",
+			policy: RetentionPolicy{MinSizeRatio: DefaultMinSizeRatio, Force: true},
+			snapshots: []Snapshot{
+				{CreatedAt: daysAgo(0), Rows: 10, ExpectedRows: 100},
+				{CreatedAt: daysAgo(1), Rows: 100},
+			},
+			wantKeep: 1,
+			wantRows: 100,
+		},
+		{
+			name:   "no checks when nothing is pruned",
+			policy: RetentionPolicy{KeepLast: 2, MinSizeRatio: DefaultMinSizeRatio},
+			snapshots: []Snapshot{
+				{CreatedAt: daysAgo(0), Rows: 10, ExpectedRows: 100},
+				{CreatedAt: daysAgo(1), Rows: 100},
+			},
+			wantKeep: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := tt.policy.Plan(tt.snapshots, now)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Plan() error = %v, wantError %v", err, tt.wantError)
+			}
+			if len(plan.Keep) != tt.wantKeep {
+				t.Errorf("Plan() kept %d snapshots, want %d", len(plan.Keep), tt.wantKeep)
+			}
+			if plan.PrunedRows() != tt.wantRows {
+				t.Errorf("Plan() pruned %d rows, want %d", plan.PrunedRows(), tt.wantRows)
+			}
+			for _, pruned := range plan.Prune {
+				if !pruned.CreatedAt.Before(plan.Cutoff()) {
+					t.Errorf("Plan() pruned snapshot from %s, which isn't older than the cutoff %s", pruned.CreatedAt, plan.Cutoff())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package bigquery
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/civil"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+// Push modes select how a MappingTableManager writes a snapshot to the
+// mapping table.
+const (
+	// PushModeStream uses the streaming inserter. A push that fails part way
+	// leaves part of the snapshot behind, and the table can't be pruned for up
+	// to 90 minutes after streaming to it.
+	PushModeStream = "stream"
+
+	// PushModeLoad writes the snapshot to a local NDJSON file and appends it
+	// with a single load job, which loads either every row or none.
+	PushModeLoad = "load"
+
+	// PushModeMerge loads the snapshot into a staging table, then MERGEs it
+	// into the mapping table, inserting only the rows that aren't there yet.
+	// It completes a snapshot left behind by an earlier failed push.
+	PushModeMerge = "merge"
+)
+
+// PushModes lists the valid push modes.
+var PushModes = []string{PushModeStream, PushModeLoad, PushModeMerge}
+
+// MappingStore is the storage for mapping snapshots. MappingTableManager
+// implements it on top of BigQuery; pkg/bigquery/filestore provides an
+// offline stand-in for tests and local development.
+type MappingStore interface {
+	// Migrate creates the mapping and run-history tables, or applies the
+	// pending MappingTableMigrations and RunHistoryTableMigrations to them.
+	// In BigQuery, it also maintains the view of the newest snapshot.
+	// Migrations that rename or drop columns are refused unless
+	// allowDestructive is set.
+	Migrate(allowDestructive bool) error
+
+	// ListMappings returns every stored mapping record.
+	ListMappings() ([]v1.TestOwnership, error)
+
+	// ListLatestMappings returns the records of the newest snapshot.
+	ListLatestMappings() ([]v1.TestOwnership, error)
+
+	// PushMappings appends a snapshot of mapping records. If the records are
+	// tagged with a run ID, pushing a run that's already in the table is a
+	// no-op.
+	PushMappings(mappings []v1.TestOwnership) error
+
+	// ListSnapshots summarizes every snapshot in the table.
+	ListSnapshots() ([]Snapshot, error)
+
+	// PruneMappings deletes the snapshots the retention policy doesn't keep,
+	// and returns the plan it followed. With dryRun, nothing is deleted.
+	PruneMappings(policy RetentionPolicy, dryRun bool) (*PrunePlan, error)
+
+	// PushRun records a mapping run in the run-history table. Recording a
+	// run that's already there is a no-op.
+	PushRun(run v1.MappingRun) error
+
+	// ListRuns returns every recorded run, newest first.
+	ListRuns() ([]v1.MappingRun, error)
+}
+
+// TestLister lists the tests seen in junit results. TestTableManager
+// implements it on top of BigQuery.
+type TestLister interface {
+	ListTests() ([]v1.TestInfo, error)
+}
+
+var (
+	_ MappingStore = &MappingTableManager{}
+	_ TestLister   = &TestTableManager{}
+)
+
+// PushAction is what pushing a run does, depending on how many of its rows
+// are already in the table.
+type PushAction int
+
+const (
+	// PushAll pushes a run that isn't in the table yet.
+	PushAll PushAction = iota
+	// PushSkip skips a run that's already in the table.
+	PushSkip
+	// PushComplete inserts the rows missing from a run left behind by a
+	// partially failed push.
+	PushComplete
+)
+
+// PlanPush decides what pushing a run of total rows does, when pushed of
+// them are already in the table.
+func PlanPush(pushed, total int) PushAction {
+	switch {
+	case pushed == 0:
+		return PushAll
+	case pushed >= total:
+		return PushSkip
+	default:
+		return PushComplete
+	}
+}
+
+// StampCreatedAt returns a copy of the mappings created at createdAt. A
+// retried push completing a run stamps the rows it adds with the run's
+// original CreatedAt, so the run stays a single snapshot.
+func StampCreatedAt(mappings []v1.TestOwnership, createdAt civil.DateTime) []v1.TestOwnership {
+	stamped := make([]v1.TestOwnership, len(mappings))
+	for i := range mappings {
+		stamped[i] = mappings[i]
+		stamped[i].CreatedAt = createdAt
+	}
+	return stamped
+}
+
+// SnapshotRunID returns the run ID shared by every record in a snapshot,
+// which is empty for snapshots from before run IDs were recorded. Records
+// from different runs can't be pushed together.
+func SnapshotRunID(mappings []v1.TestOwnership) (string, error) {
+	if len(mappings) == 0 {
+		return "", nil
+	}
+	runID := mappings[0].RunID
+	for i := range mappings {
+		if mappings[i].RunID != runID {
+			return "", fmt.Errorf("snapshot mixes records from runs %q and %q", runID, mappings[i].RunID)
+		}
+	}
+	return runID, nil
+}
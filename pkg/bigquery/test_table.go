@@ -6,76 +6,70 @@ import (
 	"strings"
 	"time"
 
+	"cloud.google.com/go/bigquery"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/api/iterator"
 
 	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
 )
 
-const testTableName = "junit"
-
-var suites = []string{
-	"openshift-tests",
-	"openshift-tests-upgrade",
-	"BakckendDisruption",
-	"Cluster upgrade",
-	"hypershift-e2e",
-	"cluster install",
-	"Operator results",
-}
-
-var ignoredTests = []string{
-	"Build image%",
-	"Find the input image%",
-	"Find all of the input images%",
-	"step graph.%",
-	"Run multi-stage test %",
-	"% was not OOMKilled%",
-	"Create the release image%",
-	"Import the release payload %",
-	"All images are built%",
-	"Tag the image %",
-	"%XXXitoring%",
-}
+const DefaultTestTableName = "junit"
 
 type TestTableManager struct {
-	ctx     context.Context
-	client  *Client
-	dataset string
+	ctx       context.Context
+	client    *Client
+	tableName string
+	config    *TestTableConfig
 }
 
-func NewTestTableManager(ctx context.Context, client *Client) *TestTableManager {
+// NewTestTableManager returns a manager listing tests from the given junit
+// table, filtered according to config. A nil config uses
+// DefaultTestTableConfig.
+func NewTestTableManager(ctx context.Context, client *Client, tableName string, config *TestTableConfig) *TestTableManager {
+	if config == nil {
+		config = DefaultTestTableConfig()
+	}
+
 	return &TestTableManager{
-		ctx:    ctx,
-		client: client,
+		ctx:       ctx,
+		client:    client,
+		tableName: tableName,
+		config:    config,
 	}
 }
 
 func (tm *TestTableManager) ListTests() ([]v1.TestInfo, error) {
 	now := time.Now()
 	log.Infof("fetching unique test/suite names from bigquery")
-	table := tm.client.bigquery.Dataset(tm.dataset).Table(testTableName)
+	table := tm.client.bigquery.Dataset(tm.client.datasetName).Table(tm.tableName)
+	tableLocator := fmt.Sprintf("%s.%s.%s", table.ProjectID, tm.client.datasetName, table.TableID)
 
-	var filter []string
-	for _, ignored := range ignoredTests {
-		filter = append(filter, fmt.Sprintf("test_name NOT LIKE '%s'", ignored))
+	if err := tm.logExcludedCounts(tableLocator); err != nil {
+		log.WithError(err).Warningf("could not count excluded tests")
 	}
 
+	metadata, err := tm.metadataColumns(table)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, params := tm.filter()
 	sql := fmt.Sprintf(`
-		SELECT DISTINCT
+		SELECT
 		    test_name as name,
-		    testsuite as suite
+		    testsuite as suite%s
 		FROM
-			%s.%s.%s
+			%s
 		WHERE
-		    testsuite IN ('%s')
-		AND
-		    %s
-		ORDER BY name, testsuite DESC`,
-		table.ProjectID, tm.client.datasetName, table.TableID, strings.Join(suites, "','"), strings.Join(filter, " AND "))
+		    testsuite IN UNNEST(@suites)
+		%s
+		GROUP BY name, suite
+		ORDER BY name, suite DESC`,
+		metadata, tableLocator, filter)
 	log.Debugf("query is %q", sql)
 
 	q := tm.client.bigquery.Query(sql)
+	q.Parameters = params
 	it, err := q.Read(tm.ctx)
 	if err != nil {
 		return nil, err
@@ -99,3 +93,110 @@ func (tm *TestTableManager) ListTests() ([]v1.TestInfo, error) {
 
 	return results, nil
 }
+
+// testMetadataColumns are the optional junit table columns that populate
+// v1.TestInfo metadata, and how each is aggregated across a test's rows.
+var testMetadataColumns = []struct {
+	name        string
+	aggregation string
+}{
+	{name: "repository", aggregation: "ANY_VALUE"},
+	{name: "file_path", aggregation: "ANY_VALUE"},
+	{name: "labels", aggregation: "ANY_VALUE"},
+	{name: "lifecycle", aggregation: "ANY_VALUE"},
+	{name: "release", aggregation: "MAX"},
+}
+
+// metadataColumns returns the select expressions for the test metadata
+// columns the junit table has. Tables without them only yield names and
+// suites.
+func (tm *TestTableManager) metadataColumns(table *bigquery.Table) (string, error) {
+	md, err := table.Metadata(tm.ctx)
+	if err != nil {
+		return "", err
+	}
+	present := make(map[string]bool, len(md.Schema))
+	for _, field := range md.Schema {
+		present[strings.ToLower(field.Name)] = true
+	}
+
+	var columns []string
+	for _, column := range testMetadataColumns {
+		if present[column.name] {
+			columns = append(columns, fmt.Sprintf("%s(%s) as %s", column.aggregation, column.name, column.name))
+		}
+	}
+	if len(columns) == 0 {
+		return "", nil
+	}
+	log.Debugf("junit table has test metadata columns %v", columns)
+
+	return ",\n\t\t    " + strings.Join(columns, ",\n\t\t    "), nil
+}
+
+// filter returns the SQL conditions excluding ignored tests, and the query
+// parameters they, and the suite allowlist, refer to.
+func (tm *TestTableManager) filter() (string, []bigquery.QueryParameter) {
+	params := []bigquery.QueryParameter{{Name: "suites", Value: tm.config.Suites}}
+
+	var filter []string
+	for i, pattern := range tm.config.IgnoredTests {
+		name := fmt.Sprintf("ignored_like_%d", i)
+		filter = append(filter, fmt.Sprintf("AND test_name NOT LIKE @%s", name))
+		params = append(params, bigquery.QueryParameter{Name: name, Value: pattern})
+	}
+	for i, expr := range tm.config.IgnoredTestRegexes {
+		name := fmt.Sprintf("ignored_regex_%d", i)
+		filter = append(filter, fmt.Sprintf("AND NOT REGEXP_CONTAINS(test_name, @%s)", name))
+		params = append(params, bigquery.QueryParameter{Name: name, Value: expr})
+	}
+
+	return strings.Join(filter, "\n\t\t"), params
+}
+
+// logExcludedCounts logs how many distinct tests in the allowed suites each
+// ignore pattern excludes.
+func (tm *TestTableManager) logExcludedCounts(tableLocator string) error {
+	_, params := tm.filter()
+
+	var patterns, counts []string
+	for i, pattern := range tm.config.IgnoredTests {
+		patterns = append(patterns, pattern)
+		counts = append(counts, fmt.Sprintf("COUNTIF(test_name LIKE @ignored_like_%d)", i))
+	}
+	for i, expr := range tm.config.IgnoredTestRegexes {
+		patterns = append(patterns, expr)
+		counts = append(counts, fmt.Sprintf("COUNTIF(REGEXP_CONTAINS(test_name, @ignored_regex_%d))", i))
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	sql := fmt.Sprintf(`
+		SELECT
+		    %s
+		FROM
+		    (SELECT DISTINCT test_name, testsuite FROM %s WHERE testsuite IN UNNEST(@suites))`,
+		strings.Join(counts, ",\n\t\t    "), tableLocator)
+	log.Debugf("query is %q", sql)
+
+	q := tm.client.bigquery.Query(sql)
+	q.Parameters = params
+	it, err := q.Read(tm.ctx)
+	if err != nil {
+		return err
+	}
+
+	var row []bigquery.Value
+	if err := it.Next(&row); err != nil {
+		return err
+	}
+	for i, pattern := range patterns {
+		log.WithFields(log.Fields{
+			"pattern":  pattern,
+			"excluded": row[i],
+		}).Infof("tests excluded by ignore pattern")
+	}
+
+	return nil
+}
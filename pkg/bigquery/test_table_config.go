@@ -0,0 +1,85 @@
+package bigquery
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+)
+
+// TestTableConfig controls which tests TestTableManager lists from the junit
+// table.
+type TestTableConfig struct {
+	// Suites is the allowlist of junit suites to include.
+	Suites []string `json:"suites"`
+
+	// IgnoredTests are SQL LIKE patterns; matching test names are excluded.
+	IgnoredTests []string `json:"ignoredTests,omitempty"`
+
+	// IgnoredTestRegexes are RE2 regular expressions; test names containing a
+	// match are excluded.
+	IgnoredTestRegexes []string `json:"ignoredTestRegexes,omitempty"`
+}
+
+// DefaultTestTableConfig returns the suites and ignored tests used when no
+// configuration is supplied.
+func DefaultTestTableConfig() *TestTableConfig {
+	return &TestTableConfig{
+		Suites: []string{
+			"openshift-tests",
+			"openshift-tests-upgrade",
+			"BackendDisruption",
+			// The suite was historically reported misspelled. Keep including
+			// it until the junit data is confirmed to use the fixed spelling.
+			"BakckendDisruption",
+			"Cluster upgrade",
+			"hypershift-e2e",
+			"cluster install",
+			"Operator results",
+		},
+		IgnoredTests: []string{
+			"Build image%",
+			"Find the input image%",
+			"Find all of the input images%",
+			"step graph.%",
+			"Run multi-stage test %",
+			"% was not OOMKilled%",
+			"Create the release image%",
+			"Import the release payload %",
+			"All images are built%",
+			"Tag the image %",
+			"%XXXitoring%",
+		},
+	}
+}
+
+// LoadTestTableConfig reads a TestTableConfig from a YAML or JSON file.
+func LoadTestTableConfig(filename string) (*TestTableConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var config TestTableConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("could not parse test table config %s: %w", filename, err)
+	}
+
+	return &config, config.Validate()
+}
+
+// Validate checks there is at least one suite, and that the regular
+// expressions compile.
+func (c *TestTableConfig) Validate() error {
+	if len(c.Suites) == 0 {
+		return fmt.Errorf("test table config must list at least one suite")
+	}
+	for _, expr := range c.IgnoredTestRegexes {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid ignored test regex: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package bigquery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTestTableConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantError string
+		wantSQL   []string
+	}{
+		{
+			name: "like patterns and regexes",
+			content: `
+suites:
+  - openshift-tests
+ignoredTests:
+  - "Build image%"
+ignoredTestRegexes:
+  - "^step graph\\."
+`,
+			wantSQL: []string{
+				"AND test_name NOT LIKE @ignored_like_0",
+				"AND NOT REGEXP_CONTAINS(test_name, @ignored_regex_0)",
+			},
+		},
+		{
+			name:      "requires suites",
+			content:   "ignoredTests: [\"foo%\"]\n",
+			wantError: "at least one suite",
+		},
+		{
+			name:      "rejects invalid regexes",
+			content:   "suites: [openshift-tests]\nignoredTestRegexes: [\"(\"]\n",
+			wantError: "invalid ignored test regex",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(file, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			config, err := LoadTestTableConfig(file)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("LoadTestTableConfig() error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadTestTableConfig() returned unexpected err: %+v", err)
+			}
+
+			tm := NewTestTableManager(context.Background(), nil, DefaultTestTableName, config)
+			sql, params := tm.filter()
+			for _, want := range tt.wantSQL {
+				if !strings.Contains(sql, want) {
+					t.Errorf("filter() = %q, want it to contain %q", sql, want)
+				}
+			}
+			if len(params) != 1+len(config.IgnoredTests)+len(config.IgnoredTestRegexes) {
+				t.Errorf("filter() returned %d parameters, want one per suite list and pattern", len(params))
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+// Package bqlookup reads test ownership from the BigQuery mapping table. It's
+// separate from package client, so tools reading a mapping file don't import
+// this repository's BigQuery table management. They still build against the
+// BigQuery client library, which the v1 API types use for the table schema:
+//
+//	store := bigquery.NewMappingTableManager(ctx, bigquery.WrapClient(bqClient, bigquery.DefaultDatasetName),
+//		bigquery.DefaultMappingTableName, bigquery.PushModeStream)
+//	lookup := bqlookup.New(store, time.Hour)
+package bqlookup
+
+import (
+	"time"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
+	"github.com/openshift-eng/ci-test-mapping/pkg/client"
+)
+
+// New returns a client.Lookup over the newest snapshot in a mapping table,
+// such as a bigquery.MappingTableManager. Only the newest snapshot is read.
+func New(store bigquery.MappingStore, refreshInterval time.Duration) *client.Cache {
+	return client.NewCache(store.ListLatestMappings, refreshInterval)
+}
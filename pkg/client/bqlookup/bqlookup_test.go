@@ -0,0 +1,41 @@
+package bqlookup
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery/filestore"
+)
+
+func TestNew(t *testing.T) {
+	dataset, err := filestore.NewDataset(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := filestore.NewMappingTableManager(dataset, bigquery.DefaultMappingTableName)
+	if err := store.Migrate(false); err != nil {
+		t.Fatal(err)
+	}
+	older := civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 1}}
+	newer := civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 2}}
+	for _, snapshot := range [][]v1.TestOwnership{
+		{{ID: "1", Name: "a", Component: "Old", CreatedAt: older}},
+		{{ID: "1", Name: "a", Component: "New", CreatedAt: newer}},
+	} {
+		if err := store.PushMappings(snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ownership, err := New(store, time.Hour).ByName("a", "")
+	if err != nil {
+		t.Fatalf("ByName() returned unexpected err: %+v", err)
+	}
+	if ownership.Component != "New" {
+		t.Errorf("ByName() returned component %q from an older snapshot, want New", ownership.Component)
+	}
+}
@@ -0,0 +1,240 @@
+// Package client is the supported way for other tools to read test
+// ownership, instead of copying the BigQuery SQL that finds a test's owner.
+// A Lookup answers questions about the newest mapping snapshot, read from a
+// mapping.json file, or from BigQuery through package bqlookup, and kept in
+// memory:
+//
+//	lookup := client.NewFileLookup("mapping.json", 10*time.Minute)
+//	ownership, err := lookup.ByName("[sig-storage] a test", "openshift-tests")
+package client
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/mapping"
+)
+
+// ErrNotFound is returned when looking up a test that isn't in the snapshot.
+var ErrNotFound = errors.New("test not found")
+
+// Lookup answers ownership questions about the tests in a mapping snapshot.
+// Lookups by ID and name include records marked StaffApprovedObsolete, so
+// callers can tell a test was removed on purpose; lookups by component and
+// capability leave them out. The returned records must not be modified.
+type Lookup interface {
+	// ByID returns the records with a stable ID, or ErrNotFound. A renamed
+	// test may have several.
+	ByID(id string) ([]v1.TestOwnership, error)
+
+	// ByName returns the record of a test, or ErrNotFound.
+	ByName(name, suite string) (*v1.TestOwnership, error)
+
+	// ByComponent returns the tests owned by a component, sorted by name and
+	// suite.
+	ByComponent(component string) ([]v1.TestOwnership, error)
+
+	// ByCapability returns the tests with a capability, sorted by name and
+	// suite.
+	ByCapability(capability string) ([]v1.TestOwnership, error)
+}
+
+// Loader loads a mapping snapshot.
+type Loader func() ([]v1.TestOwnership, error)
+
+// Cache is a Lookup over the snapshot returned by a Loader. The snapshot is
+// loaded on first use, and reloaded by the first lookup after it's older
+// than the refresh interval, while other lookups keep using the previous
+// one. If reloading fails, the previous snapshot keeps being served until
+// the next attempt, an interval later. A Cache is safe for concurrent use.
+type Cache struct {
+	load            Loader
+	refreshInterval time.Duration
+	now             func() time.Time
+
+	lock      sync.RWMutex
+	snapshot  *snapshot
+	checkedAt time.Time
+
+	refreshLock sync.Mutex
+}
+
+var _ Lookup = &Cache{}
+
+// NewCache returns a Cache over a Loader. With a zero refresh interval, the
+// snapshot is only reloaded by Refresh.
+func NewCache(load Loader, refreshInterval time.Duration) *Cache {
+	return &Cache{
+		load:            load,
+		refreshInterval: refreshInterval,
+		now:             time.Now,
+	}
+}
+
+// NewFileLookup returns a Lookup over a mapping file, such as mapping.json.
+func NewFileLookup(path string, refreshInterval time.Duration) *Cache {
+	return NewCache(func() ([]v1.TestOwnership, error) {
+		return mapping.LoadFile(path)
+	}, refreshInterval)
+}
+
+// Refresh reloads the snapshot now.
+func (c *Cache) Refresh() error {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+	_, err := c.refresh()
+	return err
+}
+
+// refresh must be called with refreshLock held.
+func (c *Cache) refresh() (*snapshot, error) {
+	mappings, err := c.load()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.checkedAt = c.now()
+	if err != nil {
+		return nil, err
+	}
+	c.snapshot = newSnapshot(mappings)
+	return c.snapshot, nil
+}
+
+// current returns the snapshot, loading it first if it was never loaded,
+// or reloading it if it's stale and no other lookup is already doing so.
+func (c *Cache) current() (*snapshot, error) {
+	c.lock.RLock()
+	s, checkedAt := c.snapshot, c.checkedAt
+	c.lock.RUnlock()
+
+	if s == nil {
+		c.refreshLock.Lock()
+		defer c.refreshLock.Unlock()
+		// Another lookup may have loaded it while we waited
+		c.lock.RLock()
+		s = c.snapshot
+		c.lock.RUnlock()
+		if s != nil {
+			return s, nil
+		}
+		return c.refresh()
+	}
+
+	if c.refreshInterval > 0 && c.now().Sub(checkedAt) >= c.refreshInterval && c.refreshLock.TryLock() {
+		defer c.refreshLock.Unlock()
+		refreshed, err := c.refresh()
+		if err != nil {
+			log.WithError(err).Warning("could not refresh mappings, using the previous snapshot")
+			return s, nil
+		}
+		return refreshed, nil
+	}
+
+	return s, nil
+}
+
+func (c *Cache) ByID(id string) ([]v1.TestOwnership, error) {
+	s, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	if len(s.byID[id]) == 0 {
+		return nil, ErrNotFound
+	}
+	return s.records(s.byID[id]), nil
+}
+
+func (c *Cache) ByName(name, suite string) (*v1.TestOwnership, error) {
+	s, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	i, ok := s.byName[testKey{name: name, suite: suite}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	record := s.mappings[i]
+	return &record, nil
+}
+
+func (c *Cache) ByComponent(component string) ([]v1.TestOwnership, error) {
+	s, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	return s.records(s.byComponent[component]), nil
+}
+
+func (c *Cache) ByCapability(capability string) ([]v1.TestOwnership, error) {
+	s, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	return s.records(s.byCapability[capability]), nil
+}
+
+type testKey struct {
+	name  string
+	suite string
+}
+
+// snapshot indexes a set of mappings. It's never modified once built.
+type snapshot struct {
+	mappings     []v1.TestOwnership
+	byID         map[string][]int
+	byName       map[testKey]int
+	byComponent  map[string][]int
+	byCapability map[string][]int
+}
+
+func newSnapshot(mappings []v1.TestOwnership) *snapshot {
+	sorted := append([]v1.TestOwnership{}, mappings...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].Suite < sorted[j].Suite
+	})
+
+	s := &snapshot{
+		mappings:     sorted,
+		byID:         make(map[string][]int),
+		byName:       make(map[testKey]int, len(sorted)),
+		byComponent:  make(map[string][]int),
+		byCapability: make(map[string][]int),
+	}
+	for i := range sorted {
+		m := &sorted[i]
+		s.byID[m.ID] = append(s.byID[m.ID], i)
+		if m.StaffApprovedObsolete {
+			// A test that's mapped again takes precedence
+			if _, ok := s.byName[testKey{name: m.Name, suite: m.Suite}]; !ok {
+				s.byName[testKey{name: m.Name, suite: m.Suite}] = i
+			}
+			continue
+		}
+		s.byName[testKey{name: m.Name, suite: m.Suite}] = i
+		s.byComponent[m.Component] = append(s.byComponent[m.Component], i)
+		seen := make(map[string]bool, len(m.Capabilities))
+		for _, capability := range m.Capabilities {
+			if !seen[capability] {
+				seen[capability] = true
+				s.byCapability[capability] = append(s.byCapability[capability], i)
+			}
+		}
+	}
+	return s
+}
+
+func (s *snapshot) records(indexes []int) []v1.TestOwnership {
+	records := make([]v1.TestOwnership, 0, len(indexes))
+	for _, i := range indexes {
+		records = append(records, s.mappings[i])
+	}
+	return records
+}
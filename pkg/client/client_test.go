@@ -0,0 +1,168 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+var testMappings = []v1.TestOwnership{
+	{ID: "1", Name: "b", Suite: "s", Component: "A", Capabilities: []string{"x", "x"}},
+	{ID: "2", Name: "a", Suite: "s", Component: "A", Capabilities: []string{"y"}},
+	{ID: "2", Name: "a-renamed", Suite: "s", Component: "B", Capabilities: []string{"x"}},
+	{ID: "3", Name: "gone", Suite: "s", Component: "A", Capabilities: []string{"x"}, StaffApprovedObsolete: true},
+}
+
+func names(mappings []v1.TestOwnership) []string {
+	var result []string
+	for _, m := range mappings {
+		result = append(result, m.Name)
+	}
+	return result
+}
+
+func TestCacheLookups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.json")
+	data, err := json.Marshal(testMappings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lookup := NewFileLookup(path, 0)
+
+	tests := []struct {
+		name      string
+		lookup    func() ([]v1.TestOwnership, error)
+		want      []string
+		wantError error
+	}{
+		{
+			name:   "by ID",
+			lookup: func() ([]v1.TestOwnership, error) { return lookup.ByID("2") },
+			want:   []string{"a", "a-renamed"},
+		},
+		{
+			name:   "obsolete by ID",
+			lookup: func() ([]v1.TestOwnership, error) { return lookup.ByID("3") },
+			want:   []string{"gone"},
+		},
+		{
+			name:      "unknown ID",
+			lookup:    func() ([]v1.TestOwnership, error) { return lookup.ByID("4") },
+			wantError: ErrNotFound,
+		},
+		{
+			name:   "by component",
+			lookup: func() ([]v1.TestOwnership, error) { return lookup.ByComponent("A") },
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "by capability",
+			lookup: func() ([]v1.TestOwnership, error) { return lookup.ByCapability("x") },
+			want:   []string{"a-renamed", "b"},
+		},
+		{
+			name:   "unknown capability",
+			lookup: func() ([]v1.TestOwnership, error) { return lookup.ByCapability("z") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.lookup()
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("lookup returned error %v, want %v", err, tt.wantError)
+			}
+			if !reflect.DeepEqual(names(got), tt.want) {
+				t.Errorf("lookup returned %v, want %v", names(got), tt.want)
+			}
+		})
+	}
+
+	ownership, err := lookup.ByName("a-renamed", "s")
+	if err != nil {
+		t.Fatalf("ByName() returned unexpected err: %+v", err)
+	}
+	if ownership.Component != "B" {
+		t.Errorf("ByName() returned component %q, want B", ownership.Component)
+	}
+	if _, err := lookup.ByName("a", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ByName() of an unknown test returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	var loads int
+	var loadErr error
+	cache := NewCache(func() ([]v1.TestOwnership, error) {
+		loads++
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		// The second snapshot adds a test to component A
+		if loads == 1 {
+			return testMappings[:1], nil
+		}
+		return testMappings[:2], nil
+	}, time.Hour)
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	count := func() int {
+		t.Helper()
+		mappings, err := cache.ByComponent("A")
+		if err != nil {
+			t.Fatalf("ByComponent() returned unexpected err: %+v", err)
+		}
+		return len(mappings)
+	}
+
+	if got := count(); got != 1 || loads != 1 {
+		t.Errorf("first lookup found %d tests after %d loads, want 1 after 1", got, loads)
+	}
+	now = now.Add(30 * time.Minute)
+	if got := count(); got != 1 || loads != 1 {
+		t.Errorf("lookup of a fresh snapshot found %d tests after %d loads, want 1 after 1", got, loads)
+	}
+	now = now.Add(30 * time.Minute)
+	if got := count(); got != 2 || loads != 2 {
+		t.Errorf("lookup of a stale snapshot found %d tests after %d loads, want 2 after 2", got, loads)
+	}
+
+	// A failed reload keeps the previous snapshot until the next attempt
+	loadErr = errors.New("unavailable")
+	now = now.Add(time.Hour)
+	if got := count(); got != 2 || loads != 3 {
+		t.Errorf("lookup after a failed reload found %d tests after %d loads, want 2 after 3", got, loads)
+	}
+	if got := count(); got != 2 || loads != 3 {
+		t.Errorf("lookup after a failed reload retried too soon, after %d loads", loads)
+	}
+
+	if err := cache.Refresh(); err == nil {
+		t.Errorf("Refresh() succeeded while loading fails")
+	}
+	loadErr = nil
+	if err := cache.Refresh(); err != nil {
+		t.Fatalf("Refresh() returned unexpected err: %+v", err)
+	}
+	if got := count(); got != 2 || loads != 5 {
+		t.Errorf("lookup after Refresh() found %d tests after %d loads, want 2 after 5", got, loads)
+	}
+}
+
+func TestCacheLoadError(t *testing.T) {
+	cache := NewCache(func() ([]v1.TestOwnership, error) {
+		return nil, errors.New("unavailable")
+	}, time.Hour)
+	if _, err := cache.ByID("1"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("ByID() without a snapshot returned %v, want the load error", err)
+	}
+}
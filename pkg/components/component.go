@@ -3,6 +3,8 @@ package components
 import (
 	"crypto/md5"
 	"fmt"
+	"sort"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -17,13 +19,71 @@ const (
 	DefaultProduct    = "OpenShift"
 )
 
+// Claimant is a component that claimed a test, along with the priority it
+// claimed it with.
+type Claimant struct {
+	Component string `json:"component"`
+	Priority  int    `json:"priority"`
+}
+
+// ConflictError is returned by IdentifyTest when more than one component
+// claims a test at the highest priority. It lists every component that
+// claimed the test, not only the tied ones, ordered by descending priority.
+type ConflictError struct {
+	Name      string     `json:"name"`
+	Suite     string     `json:"suite"`
+	Claimants []Claimant `json:"claimants"`
+}
+
+func (e *ConflictError) Error() string {
+	var claimants []string
+	for _, c := range e.Claimants {
+		claimants = append(claimants, fmt.Sprintf("%s (priority %d)", c.Component, c.Priority))
+	}
+	return fmt.Sprintf("test %q is claimed by %s - unable to resolve conflict "+
+		"-- please use priority field", e.Name, strings.Join(claimants, ", "))
+}
+
+// IdentifyTest asks every registered component whether it owns the test, and
+// returns the ownership with the highest priority. The result does not depend
+// on registry iteration order: components are consulted in name order, and a
+// tie at the highest priority is always reported as a *ConflictError.
+//
+// A [Jira:"Component"] annotation in the test name takes precedence over
+// every component's heuristics: the test belongs to the component owning that
+// Jira component. An annotation naming an unknown Jira component is logged
+// and ignored.
 func IdentifyTest(reg *registry.Registry, test *v1.TestInfo) (*v1.TestOwnership, error) {
+	names := make([]string, 0, len(reg.Components))
+	for name := range reg.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return identifyTest(reg, test, names, func(name string, component v1.Component) (*v1.TestOwnership, error) {
+		return component.IdentifyTest(test)
+	})
+}
+
+// identifyTest implements IdentifyTest, consulting only the named
+// components, in order, through claim.
+func identifyTest(reg *registry.Registry, test *v1.TestInfo, names []string,
+	claim func(name string, component v1.Component) (*v1.TestOwnership, error)) (*v1.TestOwnership, error) {
+	if jira, ok := util.ExtractJiraComponent(test.Name); ok {
+		name, component := reg.LookupJiraComponent(jira)
+		if component != nil {
+			return identifyAnnotatedTest(test, jira, name, component)
+		}
+		log.WithFields(testInfoLogFields(test)).Warningf("test is annotated with unknown jira component %q", jira)
+	}
+
 	var ownerships []*v1.TestOwnership
 
-	log.WithFields(testInfoLogFields(test)).Debugf("attempting to identify test using %d components", len(reg.Components))
-	for name, component := range reg.Components {
+	log.WithFields(testInfoLogFields(test)).Debugf("attempting to identify test using %d components", len(names))
+	for _, name := range names {
+		component := reg.Components[name]
 		log.WithFields(testInfoLogFields(test)).Tracef("checking component %q", name)
-		ownership, err := component.IdentifyTest(test)
+		ownership, err := claim(name, component)
 		if err != nil {
 			log.WithError(err).Errorf("component %q returned an error", name)
 			return nil, err
@@ -41,12 +101,39 @@ func IdentifyTest(reg *registry.Registry, test *v1.TestInfo) (*v1.TestOwnership,
 		}, nil))
 	}
 
-	return getHighestPriority(ownerships)
+	return getHighestPriority(test, ownerships)
+}
+
+// identifyAnnotatedTest assigns a test to the component owning the Jira
+// component it's annotated with. If the component claims the test itself,
+// its ownership is used; otherwise the test gets the component's extracted
+// capabilities, if it has any.
+func identifyAnnotatedTest(test *v1.TestInfo, jira, name string, component v1.Component) (*v1.TestOwnership, error) {
+	log.WithFields(testInfoLogFields(test)).Debugf("test is annotated with jira component %q owned by %q", jira, name)
+	ownership, err := component.IdentifyTest(test)
+	if err != nil {
+		log.WithError(err).Errorf("component %q returned an error", name)
+		return nil, err
+	}
+	if ownership == nil {
+		ownership = &v1.TestOwnership{
+			Name:      test.Name,
+			Component: name,
+		}
+		if extractor, ok := component.(interface {
+			ExtractCapabilities(*v1.TestInfo) []string
+		}); ok {
+			ownership.Capabilities = extractor.ExtractCapabilities(test)
+		}
+	}
+	ownership.JIRAComponent = jira
+
+	return setDefaults(test, ownership, component), nil
 }
 
 func setDefaults(testInfo *v1.TestInfo, testOwnership *v1.TestOwnership, c v1.Component) *v1.TestOwnership {
 	if testOwnership.ID == "" && c != nil {
-		testOwnership.ID = fmt.Sprintf("%x", md5.Sum([]byte(util.StableID(testInfo, nil))))
+		testOwnership.ID = fmt.Sprintf("%x", md5.Sum([]byte(c.StableID(testInfo))))
 	}
 
 	testOwnership.Kind = v1.Kind
@@ -78,17 +165,31 @@ func testInfoLogFields(testInfo *v1.TestInfo) log.Fields {
 	}
 }
 
-func getHighestPriority(ownerships []*v1.TestOwnership) (*v1.TestOwnership, error) {
+func getHighestPriority(test *v1.TestInfo, ownerships []*v1.TestOwnership) (*v1.TestOwnership, error) {
 	var highest *v1.TestOwnership
+	tied := false
 	for _, ownership := range ownerships {
-		if highest != nil && ownership.Priority == highest.Priority {
-			return nil, fmt.Errorf("test %q is claimed by %s, %s - unable to resolve conflict "+
-				"-- please use priority field", highest.Name, highest.Component, ownership.Component)
+		switch {
+		case highest == nil || ownership.Priority > highest.Priority:
+			highest = ownership
+			tied = false
+		case ownership.Priority == highest.Priority:
+			tied = true
 		}
+	}
 
-		if highest == nil || ownership.Priority > highest.Priority {
-			highest = ownership
+	if tied {
+		conflict := &ConflictError{Name: test.Name, Suite: test.Suite}
+		for _, ownership := range ownerships {
+			conflict.Claimants = append(conflict.Claimants, Claimant{
+				Component: ownership.Component,
+				Priority:  ownership.Priority,
+			})
 		}
+		sort.SliceStable(conflict.Claimants, func(i, j int) bool {
+			return conflict.Claimants[i].Priority > conflict.Claimants[j].Priority
+		})
+		return nil, conflict
 	}
 
 	return highest, nil
@@ -1,12 +1,16 @@
 package components
 
 import (
+	"crypto/md5"
+	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
 
 	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
 	"github.com/openshift-eng/ci-test-mapping/pkg/components/storage"
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
 	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
 )
 
@@ -28,6 +32,12 @@ func TestIdentifyTest(t *testing.T) {
 			wantComponent:    "Storage",
 			wantCapabilities: []string{"foobar"},
 		},
+		{
+			name:             "extracts capability annotations",
+			testInfo:         &v1.TestInfo{Name: "[sig-storage][Feature:foobar][Capability:Snapshots] component with capability"},
+			wantComponent:    "Storage",
+			wantCapabilities: []string{"foobar", "Snapshots"},
+		},
 		{
 			name:             "identifies the correct component with default capability",
 			testInfo:         &v1.TestInfo{Name: "[sig-storage] component with unknown capability"},
@@ -76,3 +86,191 @@ func TestIdentifyTest(t *testing.T) {
 		})
 	}
 }
+
+type priorityComponent struct {
+	name     string
+	priority int
+}
+
+func (c *priorityComponent) IdentifyTest(test *v1.TestInfo) (*v1.TestOwnership, error) {
+	return &v1.TestOwnership{Name: test.Name, Component: c.name, Priority: c.priority}, nil
+}
+
+func (c *priorityComponent) StableID(test *v1.TestInfo) string {
+	return test.Name
+}
+
+func (c *priorityComponent) JiraComponents() []string {
+	return []string{c.name}
+}
+
+func TestIdentifyTestConflicts(t *testing.T) {
+	tests := []struct {
+		name          string
+		priorities    map[string]int
+		wantComponent string
+		wantClaimants []Claimant
+	}{
+		{
+			name:          "highest priority wins regardless of order",
+			priorities:    map[string]int{"a": 0, "b": 0, "c": 1},
+			wantComponent: "c",
+		},
+		{
+			name:          "highest priority wins when registered first",
+			priorities:    map[string]int{"a": 1, "b": 0, "c": 0},
+			wantComponent: "a",
+		},
+		{
+			name:       "tie at highest priority reports every claimant",
+			priorities: map[string]int{"a": 1, "b": -1, "c": 1},
+			wantClaimants: []Claimant{
+				{Component: "a", Priority: 1},
+				{Component: "c", Priority: 1},
+				{Component: "b", Priority: -1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map iteration order is random, so repeat to make sure the
+			// outcome never depends on it.
+			for i := 0; i < 20; i++ {
+				reg := &registry.Registry{}
+				for name, priority := range tt.priorities {
+					reg.Register(name, &priorityComponent{name: name, priority: priority})
+				}
+
+				testOwnership, err := IdentifyTest(reg, &v1.TestInfo{Name: "a test"})
+				if tt.wantClaimants != nil {
+					var conflict *ConflictError
+					if !errors.As(err, &conflict) {
+						t.Fatalf("IdentifyTest() error = %v, want *ConflictError", err)
+					}
+					if !reflect.DeepEqual(conflict.Claimants, tt.wantClaimants) {
+						t.Fatalf("IdentifyTest() claimants = %v, want %v", conflict.Claimants, tt.wantClaimants)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("IdentifyTest() returned unexpected err: %+v", err)
+				}
+				if testOwnership.Component != tt.wantComponent {
+					t.Fatalf("IdentifyTest() gotComponent = %v, want %v", testOwnership.Component, tt.wantComponent)
+				}
+			}
+		})
+	}
+}
+
+func TestIdentifyTestStableID(t *testing.T) {
+	reg := &registry.Registry{}
+	reg.Register("Renamed", &config.Component{
+		Name:                 "Renamed",
+		DefaultJiraComponent: "Renamed",
+		Matchers:             []config.ComponentMatcher{{SIG: "sig-renamed"}},
+		Renames:              map[string]string{"[sig-renamed] old name": "[sig-renamed] new name"},
+	})
+	if err := reg.Validate(); err != nil {
+		t.Fatalf("Validate() returned unexpected err: %+v", err)
+	}
+
+	oldOwnership, err := IdentifyTest(reg, &v1.TestInfo{Name: "[sig-renamed] old name", Suite: "suite"})
+	if err != nil {
+		t.Fatalf("IdentifyTest() returned unexpected err: %+v", err)
+	}
+	newOwnership, err := IdentifyTest(reg, &v1.TestInfo{Name: "[sig-renamed] new name", Suite: "suite"})
+	if err != nil {
+		t.Fatalf("IdentifyTest() returned unexpected err: %+v", err)
+	}
+
+	wantID := fmt.Sprintf("%x", md5.Sum([]byte("suite.[sig-renamed] old name")))
+	if oldOwnership.ID != wantID || newOwnership.ID != wantID {
+		t.Errorf("IdentifyTest() got IDs %q and %q, want %q for both", oldOwnership.ID, newOwnership.ID, wantID)
+	}
+}
+
+func TestIdentifyTestJiraAnnotation(t *testing.T) {
+	reg := &registry.Registry{}
+	reg.Register("Networking / router", &config.Component{
+		Name:                 "Networking / router",
+		DefaultJiraComponent: "Networking / router",
+		Matchers:             []config.ComponentMatcher{{SIG: "sig-network-edge", Capabilities: []string{"Router"}}},
+	})
+	reg.Register("Networking / DNS", &config.Component{
+		Name:                 "Networking / DNS",
+		DefaultJiraComponent: "Networking / DNS",
+		Matchers:             []config.ComponentMatcher{{SIG: "sig-network-edge"}},
+	})
+	reg.Register("Aggregated", &config.Component{
+		Name:                 "Aggregated",
+		DefaultJiraComponent: "Aggregated",
+		Matchers:             []config.ComponentMatcher{{Include: []string{"dns"}, JiraComponent: "Networking / DNS"}},
+	})
+
+	tests := []struct {
+		name             string
+		testName         string
+		wantError        string
+		wantComponent    string
+		wantJira         string
+		wantCapabilities []string
+	}{
+		{
+			name:      "without an annotation, heuristics conflict",
+			testName:  "[sig-network-edge] router works",
+			wantError: "unable to resolve conflict",
+		},
+		{
+			name:             "annotation resolves the conflict with the claiming component's ownership",
+			testName:         `[sig-network-edge][Jira:"Networking / router"] router works`,
+			wantComponent:    "Networking / router",
+			wantJira:         "Networking / router",
+			wantCapabilities: []string{"Router"},
+		},
+		{
+			name:             "annotation assigns a test no component claims",
+			testName:         `[sig-other][Jira:"Networking / DNS"] resolver works [Capability:Resolver]`,
+			wantComponent:    "Networking / DNS",
+			wantJira:         "Networking / DNS",
+			wantCapabilities: []string{"Resolver"},
+		},
+		{
+			name:             "component owning the jira component by default is preferred",
+			testName:         `[sig-other][Jira:"Networking / DNS"] dns works`,
+			wantComponent:    "Networking / DNS",
+			wantJira:         "Networking / DNS",
+			wantCapabilities: []string{DefaultCapability},
+		},
+		{
+			name:             "unknown jira component falls back to heuristics",
+			testName:         `[sig-other][Jira:"Nonexistent"] something works`,
+			wantComponent:    DefaultComponent,
+			wantCapabilities: []string{DefaultCapability},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ownership, err := IdentifyTest(reg, &v1.TestInfo{Name: tt.testName, Suite: "openshift-tests"})
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("IdentifyTest() error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("IdentifyTest() returned unexpected err: %+v", err)
+			}
+			if ownership.Component != tt.wantComponent || ownership.JIRAComponent != tt.wantJira {
+				t.Errorf("IdentifyTest() got component %q and jira component %q, want %q and %q",
+					ownership.Component, ownership.JIRAComponent, tt.wantComponent, tt.wantJira)
+			}
+			if !reflect.DeepEqual(ownership.Capabilities, tt.wantCapabilities) {
+				t.Errorf("IdentifyTest() gotCapabilities = %v, want %v", ownership.Capabilities, tt.wantCapabilities)
+			}
+			if ownership.ID == "" || ownership.Suite != "openshift-tests" {
+				t.Errorf("IdentifyTest() did not set defaults: %+v", ownership)
+			}
+		})
+	}
+}
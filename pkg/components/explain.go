@@ -0,0 +1,213 @@
+package components
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
+	"github.com/openshift-eng/ci-test-mapping/pkg/util"
+)
+
+const (
+	ExplainFormatText = "text"
+	ExplainFormatJSON = "json"
+)
+
+// ComponentExplanation describes what a single component made of a test.
+type ComponentExplanation struct {
+	Component string `json:"component"`
+	Claimed   bool   `json:"claimed"`
+
+	// Operator, MatcherIndex and Matcher describe the configuration that
+	// matched, for components built on config.Component.
+	Operator     string                   `json:"operator,omitempty"`
+	MatcherIndex *int                     `json:"matcher_index,omitempty"`
+	Matcher      *config.ComponentMatcher `json:"matcher,omitempty"`
+
+	Priority      int      `json:"priority"`
+	JiraComponent string   `json:"jira_component,omitempty"`
+	Capabilities  []string `json:"capabilities,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// Explanation describes how a test was mapped: what every registered
+// component made of it, and the final resolution.
+type Explanation struct {
+	Name  string `json:"name"`
+	Suite string `json:"suite"`
+
+	// JiraAnnotation is the Jira component the test name is annotated with,
+	// and JiraAnnotationOwner the component owning it, if any.
+	JiraAnnotation      string `json:"jira_annotation,omitempty"`
+	JiraAnnotationOwner string `json:"jira_annotation_owner,omitempty"`
+
+	Components []ComponentExplanation `json:"components"`
+
+	// Resolution is the final ownership, unless the test has conflicting
+	// owners, in which case Conflict describes them.
+	Resolution *v1.TestOwnership `json:"resolution,omitempty"`
+	Conflict   *ConflictError    `json:"conflict,omitempty"`
+
+	// StableID is the stable ID before hashing; Resolution.ID is its md5sum.
+	StableID string `json:"stable_id,omitempty"`
+}
+
+// Explain asks every registered component, in name order, about the test,
+// and records what each one decided, along with the result of IdentifyTest.
+func Explain(reg *registry.Registry, test *v1.TestInfo) (*Explanation, error) {
+	explanation := &Explanation{
+		Name:  test.Name,
+		Suite: test.Suite,
+	}
+
+	if jira, ok := util.ExtractJiraComponent(test.Name); ok {
+		explanation.JiraAnnotation = jira
+		explanation.JiraAnnotationOwner, _ = reg.LookupJiraComponent(jira)
+	}
+
+	names := make([]string, 0, len(reg.Components))
+	for name := range reg.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		component := reg.Components[name]
+		ce := ComponentExplanation{Component: name}
+
+		if explainer, ok := component.(interface {
+			ExplainMatch(*v1.TestInfo) *config.Match
+		}); ok {
+			if match := explainer.ExplainMatch(test); match != nil {
+				ce.Operator = match.Operator
+				ce.Matcher = match.Matcher
+				if match.Index >= 0 {
+					index := match.Index
+					ce.MatcherIndex = &index
+				}
+			}
+		}
+
+		ownership, err := component.IdentifyTest(test)
+		if err != nil {
+			ce.Error = err.Error()
+		} else if ownership != nil {
+			ce.Claimed = true
+			ce.Priority = ownership.Priority
+			ce.JiraComponent = ownership.JIRAComponent
+			ce.Capabilities = ownership.Capabilities
+		}
+		explanation.Components = append(explanation.Components, ce)
+	}
+
+	ownership, err := IdentifyTest(reg, test)
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		explanation.Conflict = conflict
+		return explanation, nil
+	} else if err != nil {
+		return nil, err
+	}
+	explanation.Resolution = ownership
+
+	if winner, ok := reg.Components[ownership.Component]; ok {
+		explanation.StableID = winner.StableID(test)
+	} else {
+		explanation.StableID = util.StableID(test, nil)
+	}
+
+	return explanation, nil
+}
+
+// WriteExplanation renders the explanation as text or JSON. The text format
+// lists only the components that claimed the test, unless all is set.
+func WriteExplanation(w io.Writer, explanation *Explanation, format string, all bool) error {
+	switch format {
+	case ExplainFormatText:
+		_, err := io.WriteString(w, formatExplanationText(explanation, all))
+		return err
+	case ExplainFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(explanation)
+	default:
+		return fmt.Errorf("unknown format %q, must be one of: %s, %s", format, ExplainFormatText, ExplainFormatJSON)
+	}
+}
+
+func formatExplanationText(e *Explanation, all bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Test:  %s\n", e.Name)
+	fmt.Fprintf(&b, "Suite: %s\n", e.Suite)
+	if e.JiraAnnotation != "" {
+		owner := e.JiraAnnotationOwner
+		if owner == "" {
+			owner = "no registered component, ignored"
+		}
+		fmt.Fprintf(&b, "Jira annotation: %q (%s)\n", e.JiraAnnotation, owner)
+	}
+
+	b.WriteString("\nComponents:\n")
+	var unclaimed int
+	for _, c := range e.Components {
+		if !c.Claimed && c.Error == "" {
+			unclaimed++
+			if all {
+				fmt.Fprintf(&b, "  %s: not claimed\n", c.Component)
+			}
+			continue
+		}
+		if c.Error != "" {
+			fmt.Fprintf(&b, "  %s: error: %s\n", c.Component, c.Error)
+			continue
+		}
+
+		fmt.Fprintf(&b, "  %s: claimed\n", c.Component)
+		switch {
+		case c.Operator != "":
+			fmt.Fprintf(&b, "    matched operator %q\n", c.Operator)
+		case c.MatcherIndex != nil:
+			fmt.Fprintf(&b, "    matched matcher %d: %s\n", *c.MatcherIndex, describeMatcher(c.Matcher))
+		}
+		fmt.Fprintf(&b, "    priority: %d\n", c.Priority)
+		if c.JiraComponent != "" {
+			fmt.Fprintf(&b, "    jira component: %s\n", c.JiraComponent)
+		}
+		fmt.Fprintf(&b, "    capabilities: %s\n", strings.Join(c.Capabilities, ", "))
+	}
+	if !all {
+		fmt.Fprintf(&b, "  (%d other components did not claim the test)\n", unclaimed)
+	}
+
+	b.WriteString("\nResolution:\n")
+	if e.Conflict != nil {
+		fmt.Fprintf(&b, "  conflict: %s\n", e.Conflict.Error())
+		return b.String()
+	}
+	fmt.Fprintf(&b, "  component: %s\n", e.Resolution.Component)
+	fmt.Fprintf(&b, "  jira component: %s\n", e.Resolution.JIRAComponent)
+	fmt.Fprintf(&b, "  capabilities: %s\n", strings.Join(e.Resolution.Capabilities, ", "))
+	fmt.Fprintf(&b, "  priority: %d\n", e.Resolution.Priority)
+	fmt.Fprintf(&b, "  stable id: %s\n", e.StableID)
+	fmt.Fprintf(&b, "  id: %s\n", e.Resolution.ID)
+
+	return b.String()
+}
+
+// describeMatcher lists the fields set on a matcher, for humans.
+func describeMatcher(m *config.ComponentMatcher) string {
+	if m == nil {
+		return ""
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Sprintf("%+v", *m)
+	}
+	return string(data)
+}
@@ -0,0 +1,136 @@
+package components
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
+)
+
+func newExplainRegistry() *registry.Registry {
+	reg := &registry.Registry{}
+	reg.Register("Networking / router", &config.Component{
+		Name:                 "Networking / router",
+		DefaultJiraComponent: "Networking / router",
+		Matchers: []config.ComponentMatcher{
+			{SIG: "sig-network-edge", Include: []string{"DNS"}, JiraComponent: "Networking / DNS"},
+			{SIG: "sig-network-edge", Capabilities: []string{"Router"}, Priority: 1},
+		},
+	})
+	reg.Register("Networking", &config.Component{
+		Name:                 "Networking",
+		DefaultJiraComponent: "Networking",
+		Matchers:             []config.ComponentMatcher{{SIG: "sig-network-edge"}},
+	})
+	reg.Register("Storage", &config.Component{
+		Name:                 "Storage",
+		DefaultJiraComponent: "Storage",
+		Matchers:             []config.ComponentMatcher{{SIG: "sig-storage"}},
+	})
+	return reg
+}
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name           string
+		testName       string
+		wantClaimed    map[string]int
+		wantComponent  string
+		wantConflict   bool
+		wantInTextDump []string
+	}{
+		{
+			name:          "higher priority matcher wins",
+			testName:      "[sig-network-edge] router works [Feature:Router]",
+			wantClaimed:   map[string]int{"Networking / router": 1, "Networking": 0},
+			wantComponent: "Networking / router",
+			wantInTextDump: []string{
+				`matched matcher 1: {"sig":"sig-network-edge","capabilities":["Router"],"priority":1}`,
+				"capabilities: Router, Router",
+				"(1 other components did not claim the test)",
+				"stable id: openshift-tests.[sig-network-edge] router works [Feature:Router]",
+			},
+		},
+		{
+			name:         "conflict",
+			testName:     "[sig-network-edge] DNS works",
+			wantClaimed:  map[string]int{"Networking / router": 0, "Networking": 0},
+			wantConflict: true,
+			wantInTextDump: []string{
+				"matched matcher 0:",
+				"jira component: Networking / DNS",
+				"conflict: test",
+			},
+		},
+		{
+			name:          "unclaimed",
+			testName:      "[sig-other] something",
+			wantClaimed:   map[string]int{},
+			wantComponent: DefaultComponent,
+			wantInTextDump: []string{
+				"(3 other components did not claim the test)",
+				"component: Unknown",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explanation, err := Explain(newExplainRegistry(), &v1.TestInfo{Name: tt.testName, Suite: "openshift-tests"})
+			if err != nil {
+				t.Fatalf("Explain() returned unexpected err: %+v", err)
+			}
+
+			if len(explanation.Components) != 3 {
+				t.Errorf("Explain() explained %d components, want 3", len(explanation.Components))
+			}
+			claimed := make(map[string]int)
+			for _, c := range explanation.Components {
+				if c.Claimed {
+					claimed[c.Component] = c.Priority
+				}
+			}
+			if len(claimed) != len(tt.wantClaimed) {
+				t.Errorf("Explain() got claimants %v, want %v", claimed, tt.wantClaimed)
+			}
+			for component, priority := range tt.wantClaimed {
+				if got, ok := claimed[component]; !ok || got != priority {
+					t.Errorf("Explain() got claimants %v, want %v", claimed, tt.wantClaimed)
+				}
+			}
+
+			if tt.wantConflict {
+				if explanation.Conflict == nil || explanation.Resolution != nil {
+					t.Errorf("Explain() got resolution %+v, want a conflict", explanation.Resolution)
+				}
+			} else if explanation.Resolution == nil || explanation.Resolution.Component != tt.wantComponent {
+				t.Errorf("Explain() got resolution %+v, want component %q", explanation.Resolution, tt.wantComponent)
+			}
+
+			var text bytes.Buffer
+			if err := WriteExplanation(&text, explanation, ExplainFormatText, false); err != nil {
+				t.Fatalf("WriteExplanation() returned unexpected err: %+v", err)
+			}
+			for _, want := range tt.wantInTextDump {
+				if !strings.Contains(text.String(), want) {
+					t.Errorf("WriteExplanation() text output is missing %q:\n%s", want, text.String())
+				}
+			}
+
+			var data bytes.Buffer
+			if err := WriteExplanation(&data, explanation, ExplainFormatJSON, false); err != nil {
+				t.Fatalf("WriteExplanation() returned unexpected err: %+v", err)
+			}
+			var decoded Explanation
+			if err := json.Unmarshal(data.Bytes(), &decoded); err != nil {
+				t.Fatalf("WriteExplanation() wrote invalid JSON: %+v", err)
+			}
+			if decoded.Name != tt.testName || len(decoded.Components) != 3 {
+				t.Errorf("WriteExplanation() JSON round trip got %+v", decoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package components
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
+	"github.com/openshift-eng/ci-test-mapping/pkg/util"
+)
+
+// bracketRegexp finds the bracketed tags in a test name, such as
+// [sig-storage]. A matcher's SIG occurs in a test name exactly when it's one
+// of these tags.
+var bracketRegexp = regexp.MustCompile(`\[([^\[\]]*)\]`)
+
+// Index is a precompiled index over a registry, which finds the components,
+// and their matchers, that could claim a test without evaluating every one of
+// them. Identifying a test through the index gives the same result as
+// IdentifyTest.
+//
+// Every matcher is indexed by one thing the test must have for it to match:
+// its SIG tag, the longest of its include substrings, or its suite. Matchers
+// with none of those are evaluated for every test, as are components that
+// aren't config.Indexable, or whose Config is nil, such as those overriding
+// IdentifyTest. Operator names are indexed too.
+//
+// The registry must not be modified after the index is built. An Index is
+// safe for concurrent use.
+type Index struct {
+	reg   *registry.Registry
+	names []string
+
+	// unindexed are the components whose IdentifyTest is always called.
+	unindexed map[string]bool
+	configs   map[string]*config.Component
+
+	bySIG       map[string][]matcherRef
+	bySuite     map[string][]matcherRef
+	byOperator  map[string][]string
+	substrings  *util.SubstringSet
+	bySubstring [][]matcherRef
+	always      []matcherRef
+}
+
+type matcherRef struct {
+	component string
+	index     int
+}
+
+// NewIndex builds an index over every component in the registry.
+func NewIndex(reg *registry.Registry) *Index {
+	ix := &Index{
+		reg:        reg,
+		unindexed:  make(map[string]bool),
+		configs:    make(map[string]*config.Component),
+		bySIG:      make(map[string][]matcherRef),
+		bySuite:    make(map[string][]matcherRef),
+		byOperator: make(map[string][]string),
+	}
+
+	for name := range reg.Components {
+		ix.names = append(ix.names, name)
+	}
+	sort.Strings(ix.names)
+
+	var substrings []string
+	substringIDs := make(map[string]int)
+	for _, name := range ix.names {
+		configurable, ok := reg.Components[name].(config.Indexable)
+		if !ok || configurable.Config() == nil {
+			ix.unindexed[name] = true
+			continue
+		}
+		c := configurable.Config()
+		ix.configs[name] = c
+
+		for _, operator := range c.Operators {
+			ix.byOperator[operator] = append(ix.byOperator[operator], name)
+		}
+
+		for i, m := range c.Matchers {
+			ref := matcherRef{component: name, index: i}
+			switch {
+			case m.SIG != "" && !strings.ContainsAny(m.SIG, "[]"):
+				ix.bySIG[m.SIG] = append(ix.bySIG[m.SIG], ref)
+			case longest(m.Include) != "":
+				substring := longest(m.Include)
+				id, ok := substringIDs[substring]
+				if !ok {
+					id = len(substrings)
+					substringIDs[substring] = id
+					substrings = append(substrings, substring)
+					ix.bySubstring = append(ix.bySubstring, nil)
+				}
+				ix.bySubstring[id] = append(ix.bySubstring[id], ref)
+			case m.Suite != "":
+				ix.bySuite[m.Suite] = append(ix.bySuite[m.Suite], ref)
+			default:
+				ix.always = append(ix.always, ref)
+			}
+		}
+	}
+	ix.substrings = util.NewSubstringSet(substrings)
+
+	return ix
+}
+
+// IdentifyTest is equivalent to the package-level IdentifyTest on the
+// indexed registry, but only consults the components that could claim the
+// test, and only evaluates their candidate operators and matchers.
+func (ix *Index) IdentifyTest(test *v1.TestInfo) (*v1.TestOwnership, error) {
+	candidates := ix.candidates(test)
+
+	var names []string
+	for _, name := range ix.names {
+		if ix.unindexed[name] || candidates[name] != nil {
+			names = append(names, name)
+		}
+	}
+
+	return identifyTest(ix.reg, test, names, func(name string, component v1.Component) (*v1.TestOwnership, error) {
+		if ix.unindexed[name] {
+			return component.IdentifyTest(test)
+		}
+		return ix.configs[name].IdentifyCandidates(test, candidates[name])
+	})
+}
+
+// candidates returns, for every indexed component that could claim the test,
+// which of its operators and matchers could match it.
+func (ix *Index) candidates(test *v1.TestInfo) map[string]*config.Candidates {
+	candidates := make(map[string]*config.Candidates)
+	get := func(name string) *config.Candidates {
+		c, ok := candidates[name]
+		if !ok {
+			c = &config.Candidates{}
+			candidates[name] = c
+		}
+		return c
+	}
+	add := func(refs []matcherRef) {
+		for _, ref := range refs {
+			c := get(ref.component)
+			c.Matchers = append(c.Matchers, ref.index)
+		}
+	}
+
+	for _, operator := range util.OperatorTestNames(test.Name) {
+		for _, name := range ix.byOperator[operator] {
+			get(name).Operators = true
+		}
+	}
+	for _, tag := range bracketRegexp.FindAllStringSubmatch(test.Name, -1) {
+		add(ix.bySIG[tag[1]])
+	}
+	ix.substrings.Find(test.Name, func(id int) {
+		add(ix.bySubstring[id])
+	})
+	add(ix.bySuite[test.Suite])
+	add(ix.always)
+
+	// A tag may occur more than once in a test name
+	for _, c := range candidates {
+		sort.Ints(c.Matchers)
+		unique := c.Matchers[:0]
+		for _, index := range c.Matchers {
+			if len(unique) == 0 || index != unique[len(unique)-1] {
+				unique = append(unique, index)
+			}
+		}
+		c.Matchers = unique
+	}
+
+	return candidates
+}
+
+func longest(values []string) string {
+	var result string
+	for _, v := range values {
+		if len(v) > len(result) {
+			result = v
+		}
+	}
+	return result
+}
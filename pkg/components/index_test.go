@@ -0,0 +1,226 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
+)
+
+// corpus generates a realistic set of test names from the registry's own
+// configuration: names carrying each component's SIG tags, include
+// substrings, operator names and capability tags, mixed with names no
+// component claims and Jira annotations.
+func corpus(reg *registry.Registry, size int) []v1.TestInfo {
+	rnd := rand.New(rand.NewSource(1))
+
+	var names []string
+	for name := range reg.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fragments, operators []string
+	for _, name := range names {
+		configurable, ok := reg.Components[name].(config.Indexable)
+		if !ok || configurable.Config() == nil {
+			continue
+		}
+		c := configurable.Config()
+		operators = append(operators, c.Operators...)
+		for _, m := range c.Matchers {
+			if m.SIG != "" {
+				fragments = append(fragments, "["+m.SIG+"]")
+			}
+			fragments = append(fragments, m.Include...)
+		}
+	}
+
+	suites := []string{"openshift-tests", "openshift-tests-upgrade", "cluster install", "Operator results", "other"}
+	tags := []string{"[Serial]", "[Slow]", "[Disruptive]", "[Feature:Snapshots]", "[Capability:Router]", "[Suite:openshift/conformance/parallel]"}
+	operatorFormats := []string{
+		"[sig-arch] operator conditions %s",
+		"Operator upgrade %s",
+		"[sig-sippy] operator install %s",
+		"Build image %s from the repository",
+	}
+
+	tests := make([]v1.TestInfo, 0, size)
+	for i := 0; i < size; i++ {
+		var name string
+		switch n := rnd.Intn(10); {
+		case n == 0 && len(operators) > 0:
+			name = fmt.Sprintf(operatorFormats[rnd.Intn(len(operatorFormats))], operators[rnd.Intn(len(operators))])
+		case n == 1:
+			name = fmt.Sprintf("[sig-unknown] unclaimed test %d", i)
+		case n == 2:
+			name = fmt.Sprintf("[sig-storage] annotated test %d [Jira:%q]", i, "Networking / router")
+		default:
+			name = fmt.Sprintf("%s test %d", fragments[rnd.Intn(len(fragments))], i)
+			if rnd.Intn(3) == 0 {
+				name += " " + fragments[rnd.Intn(len(fragments))]
+			}
+		}
+		if rnd.Intn(2) == 0 {
+			name += " " + tags[rnd.Intn(len(tags))]
+		}
+		tests = append(tests, v1.TestInfo{Name: name, Suite: suites[rnd.Intn(len(suites))]})
+	}
+	return tests
+}
+
+type identifyResult struct {
+	ownership *v1.TestOwnership
+	err       error
+}
+
+func identifyAll(tests []v1.TestInfo, identify func(*v1.TestInfo) (*v1.TestOwnership, error)) []identifyResult {
+	results := make([]identifyResult, len(tests))
+	for i := range tests {
+		results[i].ownership, results[i].err = identify(&tests[i])
+	}
+	return results
+}
+
+func compareResults(tests []v1.TestInfo, got, want []identifyResult) error {
+	for i := range tests {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			return fmt.Errorf("test %q in suite %q: got %+v, %v, want %+v, %v",
+				tests[i].Name, tests[i].Suite, got[i].ownership, got[i].err, want[i].ownership, want[i].err)
+		}
+	}
+	return nil
+}
+
+func TestIndexMatchesNaive(t *testing.T) {
+	reg := registry.NewComponentRegistry()
+	reg.Register("Failing", &failingComponent{failOn: "[sig-unknown] unclaimed test 7"})
+	tests := corpus(reg, 10000)
+	tests = append(tests, v1.TestInfo{Name: "[sig-unknown] unclaimed test 7", Suite: "other"})
+
+	want := identifyAll(tests, func(test *v1.TestInfo) (*v1.TestOwnership, error) {
+		return IdentifyTest(reg, test)
+	})
+	got := identifyAll(tests, NewIndex(reg).IdentifyTest)
+
+	if err := compareResults(tests, got, want); err != nil {
+		t.Errorf("Index.IdentifyTest() differs from IdentifyTest(): %v", err)
+	}
+
+	var conflicts, claimed int
+	for _, result := range want {
+		var conflict *ConflictError
+		if errors.As(result.err, &conflict) {
+			conflicts++
+		} else if result.ownership != nil && result.ownership.Component != "Unknown" {
+			claimed++
+		}
+	}
+	if claimed == 0 || conflicts == 0 {
+		t.Errorf("corpus is not realistic: got %d claimed tests and %d conflicts", claimed, conflicts)
+	}
+}
+
+// overridingComponent is a Go component whose IdentifyTest doesn't follow its
+// configuration: it gives up the tests its matchers claim in the upgrade
+// suite, and claims every test mentioning "custom".
+type overridingComponent struct {
+	*config.Component
+}
+
+func (c *overridingComponent) IdentifyTest(test *v1.TestInfo) (*v1.TestOwnership, error) {
+	if test.Suite == "openshift-tests-upgrade" {
+		return nil, nil
+	}
+	if strings.Contains(test.Name, "custom") {
+		return &v1.TestOwnership{Name: test.Name, Component: c.Name}, nil
+	}
+	return c.Component.IdentifyTest(test)
+}
+
+// Config keeps the component out of the index, as it overrides IdentifyTest.
+func (c *overridingComponent) Config() *config.Component {
+	return nil
+}
+
+// wrappedComponent embeds a component without overriding anything.
+type wrappedComponent struct {
+	*overridingComponent
+}
+
+func TestIndexOverridingComponent(t *testing.T) {
+	newComponent := func(name string) *config.Component {
+		return &config.Component{
+			Name:                 name,
+			DefaultJiraComponent: name,
+			Matchers:             []config.ComponentMatcher{{SIG: "sig-override"}},
+		}
+	}
+	tests := []v1.TestInfo{
+		{Name: "[sig-override] claimed by the matcher", Suite: "openshift-tests"},
+		{Name: "[sig-override] given up by the override", Suite: "openshift-tests-upgrade"},
+		{Name: "[sig-other] custom test claimed by the override", Suite: "openshift-tests"},
+	}
+
+	for _, component := range []v1.Component{
+		&overridingComponent{Component: newComponent("Overriding")},
+		&wrappedComponent{overridingComponent: &overridingComponent{Component: newComponent("Wrapped")}},
+	} {
+		reg := &registry.Registry{}
+		reg.Register("Overriding", component)
+
+		want := identifyAll(tests, func(test *v1.TestInfo) (*v1.TestOwnership, error) {
+			return IdentifyTest(reg, test)
+		})
+		got := identifyAll(tests, NewIndex(reg).IdentifyTest)
+		if err := compareResults(tests, got, want); err != nil {
+			t.Errorf("Index.IdentifyTest() of %T differs from IdentifyTest(): %v", component, err)
+		}
+	}
+
+	reg := &registry.Registry{}
+	reg.Register("Overriding", &overridingComponent{Component: newComponent("Overriding")})
+	reg.Register("Declarative", &struct{ *config.Component }{newComponent("Declarative")})
+	reg.Register("Failing", &failingComponent{})
+	ix := NewIndex(reg)
+	for name, want := range map[string]bool{"Overriding": true, "Declarative": false, "Failing": true} {
+		if ix.unindexed[name] != want {
+			t.Errorf("NewIndex() left %q unindexed = %v, want %v", name, ix.unindexed[name], want)
+		}
+	}
+}
+
+func BenchmarkIdentifyTest(b *testing.B) {
+	reg := registry.NewComponentRegistry()
+	tests := corpus(reg, 2000)
+	index := NewIndex(reg)
+
+	naive := func(test *v1.TestInfo) (*v1.TestOwnership, error) {
+		return IdentifyTest(reg, test)
+	}
+	if err := compareResults(tests, identifyAll(tests, index.IdentifyTest), identifyAll(tests, naive)); err != nil {
+		b.Fatalf("Index.IdentifyTest() differs from IdentifyTest(): %v", err)
+	}
+
+	for _, bm := range []struct {
+		name     string
+		identify func(*v1.TestInfo) (*v1.TestOwnership, error)
+	}{
+		{name: "naive", identify: naive},
+		{name: "indexed", identify: index.IdentifyTest},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				test := &tests[n%len(tests)]
+				_, _ = bm.identify(test)
+			}
+		})
+	}
+}
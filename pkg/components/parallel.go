@@ -0,0 +1,96 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
+)
+
+// Result is the outcome of identifying a single test: either its ownership,
+// or the conflict between its owners.
+type Result struct {
+	Ownership *v1.TestOwnership
+	Conflict  *ConflictError
+}
+
+// TestError is returned by IdentifyTests when a component fails with a fatal
+// error, and identifies the test that caused it.
+type TestError struct {
+	Name  string
+	Suite string
+	Err   error
+}
+
+func (e *TestError) Error() string {
+	return fmt.Sprintf("could not identify test %q in suite %q: %v", e.Name, e.Suite, e.Err)
+}
+
+func (e *TestError) Unwrap() error {
+	return e.Err
+}
+
+// IdentifyTests identifies every test using a pool of workers, and an Index
+// over the registry, and returns the results in the same order as the tests.
+// They're the same as calling IdentifyTest for each test. Conflicts are
+// reported in the results rather than as errors. On the first fatal error,
+// the remaining tests are abandoned and a *TestError is returned.
+//
+// Components are consulted concurrently, so they must be safe for concurrent
+// use, as components built on config.Component are. The registry must not be
+// modified while IdentifyTests is running.
+func IdentifyTests(reg *registry.Registry, tests []v1.TestInfo, workers int) ([]Result, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]Result, len(tests))
+	indexes := make(chan int)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var firstErr error
+
+	index := NewIndex(reg)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				ownership, err := index.IdentifyTest(&tests[i])
+				var conflict *ConflictError
+				switch {
+				case errors.As(err, &conflict):
+					results[i].Conflict = conflict
+				case err != nil:
+					stopOnce.Do(func() {
+						firstErr = &TestError{Name: tests[i].Name, Suite: tests[i].Suite, Err: err}
+						close(stop)
+					})
+					return
+				default:
+					results[i].Ownership = ownership
+				}
+			}
+		}()
+	}
+
+send:
+	for i := range tests {
+		select {
+		case indexes <- i:
+		case <-stop:
+			break send
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
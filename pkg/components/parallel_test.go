@@ -0,0 +1,94 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
+)
+
+type failingComponent struct {
+	failOn string
+	calls  int32
+}
+
+func (c *failingComponent) IdentifyTest(test *v1.TestInfo) (*v1.TestOwnership, error) {
+	atomic.AddInt32(&c.calls, 1)
+	if test.Name == c.failOn {
+		return nil, errors.New("boom")
+	}
+	return nil, nil
+}
+
+func (c *failingComponent) StableID(test *v1.TestInfo) string {
+	return test.Name
+}
+
+func (c *failingComponent) JiraComponents() []string {
+	return nil
+}
+
+func TestIdentifyTests(t *testing.T) {
+	reg := registry.NewComponentRegistry()
+
+	var tests []v1.TestInfo
+	for i := 0; i < 200; i++ {
+		tests = append(tests,
+			v1.TestInfo{Name: fmt.Sprintf("[sig-storage] test %d [Feature:Snapshots]", i), Suite: "openshift-tests"},
+			v1.TestInfo{Name: fmt.Sprintf("[sig-network] test %d", i), Suite: "openshift-tests"},
+			v1.TestInfo{Name: fmt.Sprintf("[sig-unknown] test %d", i), Suite: "openshift-tests"},
+		)
+	}
+
+	var want []Result
+	for i := range tests {
+		ownership, err := IdentifyTest(reg, &tests[i])
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			want = append(want, Result{Conflict: conflict})
+		} else if err != nil {
+			t.Fatalf("IdentifyTest() returned unexpected err: %+v", err)
+		} else {
+			want = append(want, Result{Ownership: ownership})
+		}
+	}
+
+	for _, workers := range []int{0, 1, 4, 16} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			got, err := IdentifyTests(reg, tests, workers)
+			if err != nil {
+				t.Fatalf("IdentifyTests() returned unexpected err: %+v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("IdentifyTests() results differ from calling IdentifyTest serially")
+			}
+		})
+	}
+}
+
+func TestIdentifyTestsStopsOnError(t *testing.T) {
+	failing := &failingComponent{failOn: "test 10"}
+	reg := &registry.Registry{}
+	reg.Register("Failing", failing)
+
+	var tests []v1.TestInfo
+	for i := 0; i < 10000; i++ {
+		tests = append(tests, v1.TestInfo{Name: fmt.Sprintf("test %d", i), Suite: "suite"})
+	}
+
+	_, err := IdentifyTests(reg, tests, 4)
+	var testErr *TestError
+	if !errors.As(err, &testErr) {
+		t.Fatalf("IdentifyTests() error = %v, want a *TestError", err)
+	}
+	if testErr.Name != "test 10" || testErr.Suite != "suite" {
+		t.Errorf("IdentifyTests() reported test %q in suite %q, want %q in %q", testErr.Name, testErr.Suite, "test 10", "suite")
+	}
+	if calls := atomic.LoadInt32(&failing.calls); calls >= int32(len(tests)) {
+		t.Errorf("IdentifyTests() identified all %d tests after a fatal error", calls)
+	}
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"path"
+	"regexp"
 	"strings"
+	"sync"
 
 	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
 	"github.com/openshift-eng/ci-test-mapping/pkg/util"
@@ -11,75 +15,277 @@ import (
 // own component implementation. It includes a matcher help that will identify
 // if a test belongs to a sig, operator, as well as simple substring matching.
 // Components do not need to use this framework, it's an optional add-on.
+//
+// Component also implements v1.Component, so a component defined entirely in
+// configuration (see LoadComponents) can be registered without any Go code.
 type Component struct {
-	Name                 string
-	DefaultJiraComponent string
-	Matchers             []ComponentMatcher
-	Operators            []string
+	Name                 string             `json:"name"`
+	DefaultJiraComponent string             `json:"defaultJiraComponent"`
+	Matchers             []ComponentMatcher `json:"matchers,omitempty"`
+	Operators            []string           `json:"operators,omitempty"`
+
+	// CapabilityFields are bracketed test name fields whose values become
+	// capabilities of the tests this component claims, e.g. "Testpattern"
+	// for [Testpattern: Dynamic PV]. They're extracted in addition to
+	// DefaultCapabilityFields.
+	CapabilityFields []string `json:"capabilityFields,omitempty"`
+
+	// CapabilityRules assign additional capabilities to any test this
+	// component claims, based on substrings of the test name. They're
+	// applied in addition to DefaultCapabilityRules.
+	CapabilityRules []CapabilityRule `json:"capabilityRules,omitempty"`
+
+	// DisableDefaultCapabilities turns off DefaultCapabilityFields and
+	// DefaultCapabilityRules, so only the component's own fields and rules
+	// are used.
+	DisableDefaultCapabilities bool `json:"disableDefaultCapabilities,omitempty"`
+
+	// Capabilities is the component's capability catalog. When it isn't
+	// empty, the mapper flags any capability assigned to the component's
+	// tests that isn't declared here.
+	Capabilities []CapabilityDefinition `json:"capabilities,omitempty"`
+
+	// Renames records tests that have been renamed, keyed by the old name
+	// with the new name as the value, so StableID keeps returning the ID of
+	// the original name. See util.RenameMapper for the rules the table must
+	// follow.
+	Renames map[string]string `json:"renames,omitempty"`
+
+	compileOnce  sync.Once
+	compiled     []compiledMatcher
+	renameMapper func(string) string
+	compileErr   error
 }
 
 // ComponentMatcher is used to match against a TestInfo struct. Note the fields SIG,
-// Suite, Include, and Exclude are ANDed together. That is, all that have values must
-// match.  For include  and exclude, the individual items in the array are ANDed. That
-// is, if you  specify multiple substrings, all must match. Use separate component
-// matchers for an OR operation.
+// Suite, SuitePatterns, Include, Exclude, IncludeRegex and ExcludeRegex are ANDed
+// together. That is, all that have values must match.  For include  and exclude, the
+// individual items in the array are ANDed. That is, if you  specify multiple substrings
+// or expressions, all must match. Use separate component matchers for an OR operation.
+//
+// SuitePatterns are shell globs (see path.Match); the suite must match at least one
+// of them. Regular expressions use RE2 syntax and are compiled once per component.
+//
+// The metadata fields Repositories, FilePaths, Labels, Lifecycles and Releases are
+// ANDed with the rest too, and the test must have at least one of the listed values.
+// FilePaths are shell globs matched against the test's file path and each of its parent
+// directories, so "test/extended/storage" claims every test defined under it. All of
+// the Annotations must be present on the test with the given values. Tests whose
+// source doesn't provide the metadata never match these fields.
 //
 // The second set  of fields are metadata used to assign ownership.
 type ComponentMatcher struct {
-	SIG     string
-	Suite   string
-	Include []string
-	Exclude []string
+	SIG           string   `json:"sig,omitempty"`
+	Suite         string   `json:"suite,omitempty"`
+	SuitePatterns []string `json:"suitePatterns,omitempty"`
+	Include       []string `json:"include,omitempty"`
+	Exclude       []string `json:"exclude,omitempty"`
+	IncludeRegex  []string `json:"includeRegex,omitempty"`
+	ExcludeRegex  []string `json:"excludeRegex,omitempty"`
+
+	Repositories []string          `json:"repositories,omitempty"`
+	FilePaths    []string          `json:"filePaths,omitempty"`
+	Labels       []string          `json:"labels,omitempty"`
+	Lifecycles   []string          `json:"lifecycles,omitempty"`
+	Releases     []string          `json:"releases,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
 
-	JiraComponent string
-	Capabilities  []string
-	Priority      int
+	JiraComponent string   `json:"jiraComponent,omitempty"`
+	Capabilities  []string `json:"capabilities,omitempty"`
+	Priority      int      `json:"priority,omitempty"`
+}
+
+// CapabilityDefinition declares a capability in a component's catalog.
+type CapabilityDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// CapabilityRule adds Capability to a test when its name contains Substring.
+type CapabilityRule struct {
+	Substring  string `json:"substring"`
+	Capability string `json:"capability"`
+}
+
+// DefaultCapabilityFields are the bracketed test name fields every component
+// extracts capabilities from, unless it sets DisableDefaultCapabilities.
+var DefaultCapabilityFields = []string{"Feature", "Capability"}
+
+// DefaultCapabilityRules are the capability rules every component applies,
+// unless it sets DisableDefaultCapabilities.
+var DefaultCapabilityRules = []CapabilityRule{
+	{Substring: "clusteroperator/", Capability: "Operator"},
+	{Substring: "alert/", Capability: "Alerts"},
+}
+
+// Match describes which part of a component's configuration matched a test.
+type Match struct {
+	// Operator is set when the test matched one of the component's operators.
+	Operator string
+
+	// Index is the position in Matchers of the matcher that matched, or -1
+	// when the test matched an operator.
+	Index int
+
+	// Matcher is the matcher that matched. For operator tests, it's built
+	// from the component's defaults and the operator's capabilities.
+	Matcher *ComponentMatcher
 }
 
 func (c *Component) FindMatch(test *v1.TestInfo) *ComponentMatcher {
-	if ok, capabilities := c.IsOperatorTest(test); ok {
-		return &ComponentMatcher{
-			JiraComponent: c.DefaultJiraComponent,
-			Capabilities:  capabilities,
-		}
+	if match := c.ExplainMatch(test); match != nil {
+		return match.Matcher
 	}
+	return nil
+}
 
-	// Check if any of the Matchers match the given test
-	for _, m := range c.Matchers {
-		sigMatch := true
-		suiteMatch := true
-		incSubstrMatch := true
-		excSubstrMatch := true
+// ExplainMatch is like FindMatch, but also reports which operator or matcher
+// matched the test.
+func (c *Component) ExplainMatch(test *v1.TestInfo) *Match {
+	return c.match(test, nil)
+}
 
-		if m.SIG != "" {
-			sigMatch = util.IsSigTest(test.Name, m.SIG)
-		}
+// Candidates restricts matching to the parts of a component's configuration
+// that could match a test, as determined by an index over many components.
+type Candidates struct {
+	// Operators is set when the test refers to one of the component's
+	// operators.
+	Operators bool
 
-		if m.Suite != "" {
-			suiteMatch = m.IsSuiteTest(test)
-		}
+	// Matchers are the indexes, in increasing order, of the matchers that
+	// could match.
+	Matchers []int
+}
 
-		if len(m.Include) > 0 {
-			incSubstrMatch = m.IsSubstringTest(test)
-		}
+// Indexable is implemented by components whose results are determined by
+// their configuration, so an index can tell which of their operators and
+// matchers could match a test without evaluating them, and then call
+// IdentifyCandidates. Only those whose Config isn't nil are indexed.
+type Indexable interface {
+	Config() *Component
+	Indexable()
+}
+
+var _ Indexable = &Component{}
+
+// Config returns the component's configuration. Go components that embed
+// Component and override IdentifyTest must also override Config to return
+// nil, so they aren't indexed, and IdentifyTest is always called instead.
+func (c *Component) Config() *Component {
+	return c
+}
+
+// Indexable marks Component as an Indexable.
+func (c *Component) Indexable() {}
 
-		if len(m.Exclude) > 0 {
-			excSubstrMatch = !m.IsSubstringTest(test)
+// IdentifyCandidates is IdentifyTest, evaluating only the operators and
+// matchers in candidates. The result is the same as IdentifyTest's as long as
+// none of the others can match the test.
+func (c *Component) IdentifyCandidates(test *v1.TestInfo, candidates *Candidates) (*v1.TestOwnership, error) {
+	return c.identify(test, c.match(test, candidates))
+}
+
+// match returns the first operator, then matcher, matching the test. If
+// candidates isn't nil, only the candidate operators and matchers are
+// evaluated.
+func (c *Component) match(test *v1.TestInfo, candidates *Candidates) *Match {
+	if candidates == nil || candidates.Operators {
+		for _, operator := range c.Operators {
+			// OpenShift tests related to operators (install, upgrade, etc)
+			if isOperatorTest, capabilities := util.IdentifyOperatorTest(operator, test.Name); isOperatorTest {
+				return &Match{
+					Operator: operator,
+					Index:    -1,
+					Matcher: &ComponentMatcher{
+						JiraComponent: c.DefaultJiraComponent,
+						Capabilities:  capabilities,
+					},
+				}
+			}
 		}
+	}
 
-		// AND the three match results together
-		if sigMatch && suiteMatch && incSubstrMatch && excSubstrMatch {
-			return &m
+	// Invalid expressions are reported by Validate; until then the matchers
+	// using them simply never match.
+	c.compile()
+
+	// Check if any of the Matchers match the given test
+	if candidates == nil {
+		for i := range c.Matchers {
+			if c.matches(test, i) {
+				m := c.Matchers[i]
+				return &Match{Index: i, Matcher: &m}
+			}
+		}
+		return nil
+	}
+	for _, i := range candidates.Matchers {
+		if c.matches(test, i) {
+			m := c.Matchers[i]
+			return &Match{Index: i, Matcher: &m}
 		}
 	}
 
 	return nil
 }
 
+// matches reports whether the matcher at index i matches the test.
+func (c *Component) matches(test *v1.TestInfo, i int) bool {
+	m := &c.Matchers[i]
+	sigMatch := true
+	suiteMatch := true
+	incSubstrMatch := true
+	excSubstrMatch := true
+	regexMatch := true
+	metadataMatch := true
+
+	if m.SIG != "" {
+		sigMatch = util.IsSigTest(test.Name, m.SIG)
+	}
+
+	if m.Suite != "" {
+		suiteMatch = m.IsSuiteTest(test)
+	}
+
+	if len(m.SuitePatterns) > 0 {
+		suiteMatch = suiteMatch && m.IsSuitePatternTest(test)
+	}
+
+	if len(m.Include) > 0 {
+		incSubstrMatch = m.IsSubstringTest(test)
+	}
+
+	if len(m.Exclude) > 0 {
+		excSubstrMatch = !m.IsSubstringTest(test)
+	}
+
+	if len(m.IncludeRegex) > 0 || len(m.ExcludeRegex) > 0 {
+		regexMatch = i < len(c.compiled) && c.compiled[i].matches(test.Name)
+	}
+
+	if m.hasMetadata() {
+		metadataMatch = m.IsMetadataTest(test)
+	}
+
+	// AND the match results together
+	return sigMatch && suiteMatch && incSubstrMatch && excSubstrMatch && regexMatch && metadataMatch
+}
+
 func (cm *ComponentMatcher) IsSuiteTest(test *v1.TestInfo) bool {
 	return test.Suite == cm.Suite
 }
 
+// IsSuitePatternTest reports whether the test's suite matches any of the
+// matcher's suite globs.
+func (cm *ComponentMatcher) IsSuitePatternTest(test *v1.TestInfo) bool {
+	for _, pattern := range cm.SuitePatterns {
+		if ok, err := path.Match(pattern, test.Suite); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (cm *ComponentMatcher) IsSubstringTest(test *v1.TestInfo) bool {
 	for _, str := range cm.Include {
 		if !strings.Contains(test.Name, str) {
@@ -89,6 +295,71 @@ func (cm *ComponentMatcher) IsSubstringTest(test *v1.TestInfo) bool {
 	return true
 }
 
+func (cm *ComponentMatcher) hasMetadata() bool {
+	return len(cm.Repositories) > 0 || len(cm.FilePaths) > 0 || len(cm.Labels) > 0 ||
+		len(cm.Lifecycles) > 0 || len(cm.Releases) > 0 || len(cm.Annotations) > 0
+}
+
+// IsMetadataTest reports whether the test's metadata satisfies every metadata
+// field set on the matcher.
+func (cm *ComponentMatcher) IsMetadataTest(test *v1.TestInfo) bool {
+	if len(cm.Repositories) > 0 && !containsString(cm.Repositories, test.Repository) {
+		return false
+	}
+	if len(cm.FilePaths) > 0 && !cm.IsFilePathTest(test) {
+		return false
+	}
+	if len(cm.Labels) > 0 && !containsAny(cm.Labels, test.Labels) {
+		return false
+	}
+	if len(cm.Lifecycles) > 0 && !containsString(cm.Lifecycles, test.Lifecycle) {
+		return false
+	}
+	if len(cm.Releases) > 0 && !containsString(cm.Releases, test.Release) {
+		return false
+	}
+	for k, v := range cm.Annotations {
+		if value, ok := test.Annotations[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFilePathTest reports whether the test's file, or any directory containing
+// it, matches one of the matcher's file path globs.
+func (cm *ComponentMatcher) IsFilePathTest(test *v1.TestInfo) bool {
+	if test.FilePath == "" {
+		return false
+	}
+	for p := path.Clean(test.FilePath); p != "." && p != "/"; p = path.Dir(p) {
+		for _, pattern := range cm.FilePaths {
+			if ok, err := path.Match(path.Clean(pattern), p); err == nil && ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, c := range candidates {
+		if containsString(values, c) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Component) IsOperatorTest(test *v1.TestInfo) (bool, []string) {
 	for _, operator := range c.Operators {
 		// OpenShift tests related to operators (install, upgrade, etc)
@@ -99,3 +370,185 @@ func (c *Component) IsOperatorTest(test *v1.TestInfo) (bool, []string) {
 
 	return false, nil
 }
+
+// Validate checks that every suite and file path pattern and regular expression in the
+// component's matchers is well-formed, and that the rename table is valid. If the
+// component declares a capability catalog, the capabilities its matchers and
+// capability rules assign must be in it.
+func (c *Component) Validate() error {
+	c.compile()
+	if c.compileErr != nil {
+		return c.compileErr
+	}
+
+	if len(c.Capabilities) == 0 {
+		return nil
+	}
+	declared := make(map[string]bool, len(c.Capabilities))
+	for _, capability := range c.Capabilities {
+		if capability.Name == "" {
+			return fmt.Errorf("component %q: capability catalog has an entry without a name", c.Name)
+		}
+		if declared[capability.Name] {
+			return fmt.Errorf("component %q: capability %q is declared more than once", c.Name, capability.Name)
+		}
+		declared[capability.Name] = true
+	}
+	for i, m := range c.Matchers {
+		for _, capability := range m.Capabilities {
+			if !declared[capability] {
+				return fmt.Errorf("component %q matcher %d: capability %q is not declared", c.Name, i, capability)
+			}
+		}
+	}
+	for _, rule := range c.CapabilityRules {
+		if !declared[rule.Capability] {
+			return fmt.Errorf("component %q capability rule for %q: capability %q is not declared", c.Name, rule.Substring, rule.Capability)
+		}
+	}
+
+	return nil
+}
+
+// CapabilityCatalog returns the capabilities the component declares, if any.
+func (c *Component) CapabilityCatalog() []CapabilityDefinition {
+	return c.Capabilities
+}
+
+type compiledMatcher struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// matches reports whether name matches every include expression and does not
+// match every exclude expression.
+func (cm compiledMatcher) matches(name string) bool {
+	for _, re := range cm.include {
+		if !re.MatchString(name) {
+			return false
+		}
+	}
+	if len(cm.exclude) == 0 {
+		return true
+	}
+	for _, re := range cm.exclude {
+		if !re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Component) compile() {
+	c.compileOnce.Do(func() {
+		compiled := make([]compiledMatcher, len(c.Matchers))
+		for i, m := range c.Matchers {
+			for _, pattern := range m.SuitePatterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					c.compileErr = fmt.Errorf("component %q matcher %d: invalid suite pattern %q: %w", c.Name, i, pattern, err)
+					return
+				}
+			}
+			for _, pattern := range m.FilePaths {
+				if _, err := path.Match(pattern, ""); err != nil {
+					c.compileErr = fmt.Errorf("component %q matcher %d: invalid file path pattern %q: %w", c.Name, i, pattern, err)
+					return
+				}
+			}
+			for _, expr := range m.IncludeRegex {
+				re, err := regexp.Compile(expr)
+				if err != nil {
+					c.compileErr = fmt.Errorf("component %q matcher %d: invalid include regex: %w", c.Name, i, err)
+					return
+				}
+				compiled[i].include = append(compiled[i].include, re)
+			}
+			for _, expr := range m.ExcludeRegex {
+				re, err := regexp.Compile(expr)
+				if err != nil {
+					c.compileErr = fmt.Errorf("component %q matcher %d: invalid exclude regex: %w", c.Name, i, err)
+					return
+				}
+				compiled[i].exclude = append(compiled[i].exclude, re)
+			}
+		}
+		mapper, err := util.RenameMapper(c.Renames)
+		if err != nil {
+			c.compileErr = fmt.Errorf("component %q: invalid renames: %w", c.Name, err)
+			return
+		}
+		c.compiled = compiled
+		c.renameMapper = mapper
+	})
+}
+
+// IdentifyTest implements v1.Component using the configured matchers. Go
+// components that embed Component may override it with custom logic.
+func (c *Component) IdentifyTest(test *v1.TestInfo) (*v1.TestOwnership, error) {
+	return c.identify(test, c.ExplainMatch(test))
+}
+
+func (c *Component) identify(test *v1.TestInfo, match *Match) (*v1.TestOwnership, error) {
+	if match != nil {
+		matcher := match.Matcher
+		jira := matcher.JiraComponent
+		if jira == "" {
+			jira = c.DefaultJiraComponent
+		}
+		var capabilities []string
+		capabilities = append(capabilities, matcher.Capabilities...)
+		capabilities = append(capabilities, c.ExtractCapabilities(test)...)
+		return &v1.TestOwnership{
+			Name:          test.Name,
+			Component:     c.Name,
+			JIRAComponent: jira,
+			Priority:      matcher.Priority,
+			Capabilities:  capabilities,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// StableID returns the suite and original name of the test, following the
+// component's rename table.
+func (c *Component) StableID(test *v1.TestInfo) string {
+	c.compile()
+	return util.StableID(test, c.renameMapper)
+}
+
+func (c *Component) JiraComponents() (components []string) {
+	components = []string{c.DefaultJiraComponent}
+	for _, m := range c.Matchers {
+		components = append(components, m.JiraComponent)
+	}
+
+	return components
+}
+
+// ExtractCapabilities returns the capabilities found in the test's name: the
+// values of the capability fields, followed by the capabilities of every
+// matching capability rule. Defaults come before the component's own fields
+// and rules.
+func (c *Component) ExtractCapabilities(test *v1.TestInfo) []string {
+	var capabilities []string
+
+	fields := c.CapabilityFields
+	rules := c.CapabilityRules
+	if !c.DisableDefaultCapabilities {
+		fields = append(append([]string{}, DefaultCapabilityFields...), fields...)
+		rules = append(append([]CapabilityRule{}, DefaultCapabilityRules...), rules...)
+	}
+
+	for _, field := range fields {
+		capabilities = append(capabilities, util.ExtractTestField(test.Name, field)...)
+	}
+
+	for _, rule := range rules {
+		if strings.Contains(test.Name, rule.Substring) {
+			capabilities = append(capabilities, rule.Capability)
+		}
+	}
+
+	return capabilities
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+func TestFindMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		matcher   ComponentMatcher
+		testInfo  *v1.TestInfo
+		wantMatch bool
+	}{
+		{
+			name:      "include regex matches",
+			matcher:   ComponentMatcher{IncludeRegex: []string{`ingress-to-.*disruption`}},
+			testInfo:  &v1.TestInfo{Name: "[sig-network-edge] ingress-to-oauth-server disruption"},
+			wantMatch: true,
+		},
+		{
+			name:      "include regex does not match",
+			matcher:   ComponentMatcher{IncludeRegex: []string{`^ingress-to-`}},
+			testInfo:  &v1.TestInfo{Name: "[sig-network-edge] ingress-to-oauth-server disruption"},
+			wantMatch: false,
+		},
+		{
+			name:      "exclude regex rejects",
+			matcher:   ComponentMatcher{SIG: "sig-network", ExcludeRegex: []string{`\[Skipped:Network/OVN\w+\]`}},
+			testInfo:  &v1.TestInfo{Name: "[sig-network] foo [Skipped:Network/OVNKubernetes]"},
+			wantMatch: false,
+		},
+		{
+			name:      "exclude regex requires all expressions to reject",
+			matcher:   ComponentMatcher{SIG: "sig-network", ExcludeRegex: []string{`Skipped`, `Disruptive`}},
+			testInfo:  &v1.TestInfo{Name: "[sig-network] foo [Skipped:Network/OVNKubernetes]"},
+			wantMatch: true,
+		},
+		{
+			name:      "suite glob matches",
+			matcher:   ComponentMatcher{SuitePatterns: []string{"hypershift-*", "Cluster upgrade"}},
+			testInfo:  &v1.TestInfo{Name: "TestCreateCluster", Suite: "hypershift-e2e"},
+			wantMatch: true,
+		},
+		{
+			name:      "suite glob is ANDed with exact suite",
+			matcher:   ComponentMatcher{Suite: "openshift-tests", SuitePatterns: []string{"hypershift-*"}},
+			testInfo:  &v1.TestInfo{Name: "TestCreateCluster", Suite: "hypershift-e2e"},
+			wantMatch: false,
+		},
+		{
+			name:      "regex is ANDed with substrings",
+			matcher:   ComponentMatcher{Include: []string{"etcd"}, IncludeRegex: []string{`leader elections? `}},
+			testInfo:  &v1.TestInfo{Name: "[sig-etcd] leader changes are not excessive"},
+			wantMatch: false,
+		},
+		{
+			name:      "file path matches a parent directory",
+			matcher:   ComponentMatcher{FilePaths: []string{"test/extended/storage"}},
+			testInfo:  &v1.TestInfo{Name: "a test", FilePath: "test/extended/storage/csi/driver.go"},
+			wantMatch: true,
+		},
+		{
+			name:      "file path glob matches",
+			matcher:   ComponentMatcher{FilePaths: []string{"test/extended/*/router.go"}},
+			testInfo:  &v1.TestInfo{Name: "a test", FilePath: "test/extended/router/router.go"},
+			wantMatch: true,
+		},
+		{
+			name:      "file path does not match a sibling directory",
+			matcher:   ComponentMatcher{FilePaths: []string{"test/extended/storage"}},
+			testInfo:  &v1.TestInfo{Name: "a test", FilePath: "test/extended/storage-other/driver.go"},
+			wantMatch: false,
+		},
+		{
+			name:      "file path never matches tests without one",
+			matcher:   ComponentMatcher{FilePaths: []string{"*"}},
+			testInfo:  &v1.TestInfo{Name: "a test"},
+			wantMatch: false,
+		},
+		{
+			name:      "any label matches",
+			matcher:   ComponentMatcher{Labels: []string{"Conformance", "Networking"}},
+			testInfo:  &v1.TestInfo{Name: "a test", Labels: []string{"Serial", "Networking"}},
+			wantMatch: true,
+		},
+		{
+			name:      "metadata is ANDed with the sig",
+			matcher:   ComponentMatcher{SIG: "sig-network", Labels: []string{"Networking"}},
+			testInfo:  &v1.TestInfo{Name: "[sig-storage] a test", Labels: []string{"Networking"}},
+			wantMatch: false,
+		},
+		{
+			name: "repository, lifecycle, release and annotations match",
+			matcher: ComponentMatcher{
+				Repositories: []string{"openshift/origin"},
+				Lifecycles:   []string{"blocking"},
+				Releases:     []string{"4.15", "4.16"},
+				Annotations:  map[string]string{"team": "storage"},
+			},
+			testInfo: &v1.TestInfo{
+				Name:        "a test",
+				Repository:  "openshift/origin",
+				Lifecycle:   "blocking",
+				Release:     "4.16",
+				Annotations: map[string]string{"team": "storage", "other": "value"},
+			},
+			wantMatch: true,
+		},
+		{
+			name:      "annotation value must match",
+			matcher:   ComponentMatcher{Annotations: map[string]string{"team": "storage"}},
+			testInfo:  &v1.TestInfo{Name: "a test", Annotations: map[string]string{"team": "network"}},
+			wantMatch: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Component{Name: "Test", DefaultJiraComponent: "Test", Matchers: []ComponentMatcher{tt.matcher}}
+			if err := c.Validate(); err != nil {
+				t.Fatalf("Validate() returned unexpected err: %+v", err)
+			}
+			if got := c.FindMatch(tt.testInfo) != nil; got != tt.wantMatch {
+				t.Errorf("FindMatch() matched = %v, want %v", got, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		matcher   ComponentMatcher
+		wantError string
+	}{
+		{
+			name:      "invalid include regex",
+			matcher:   ComponentMatcher{IncludeRegex: []string{`(unclosed`}},
+			wantError: "invalid include regex",
+		},
+		{
+			name:      "invalid exclude regex",
+			matcher:   ComponentMatcher{ExcludeRegex: []string{`[z-a]`}},
+			wantError: "invalid exclude regex",
+		},
+		{
+			name:      "invalid suite pattern",
+			matcher:   ComponentMatcher{SuitePatterns: []string{"[openshift"}},
+			wantError: "invalid suite pattern",
+		},
+		{
+			name:      "invalid file path pattern",
+			matcher:   ComponentMatcher{FilePaths: []string{"test/[extended"}},
+			wantError: "invalid file path pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Component{Name: "Test", Matchers: []ComponentMatcher{tt.matcher}}
+			err := c.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantError)
+			}
+			if c.FindMatch(&v1.TestInfo{Name: "anything"}) != nil {
+				t.Errorf("FindMatch() matched with an invalid matcher")
+			}
+		})
+	}
+}
+
+func TestValidateCapabilityCatalog(t *testing.T) {
+	tests := []struct {
+		name      string
+		component *Component
+		wantError string
+	}{
+		{
+			name: "declared capabilities",
+			component: &Component{
+				Matchers:        []ComponentMatcher{{SIG: "sig-network-edge", Capabilities: []string{"Router"}}},
+				CapabilityRules: []CapabilityRule{{Substring: "ingress", Capability: "Ingress"}},
+				Capabilities:    []CapabilityDefinition{{Name: "Router"}, {Name: "Ingress"}},
+			},
+		},
+		{
+			name: "no catalog",
+			component: &Component{
+				Matchers: []ComponentMatcher{{SIG: "sig-network-edge", Capabilities: []string{"Router"}}},
+			},
+		},
+		{
+			name: "matcher capability not declared",
+			component: &Component{
+				Matchers:     []ComponentMatcher{{SIG: "sig-network-edge", Capabilities: []string{"Routre"}}},
+				Capabilities: []CapabilityDefinition{{Name: "Router"}},
+			},
+			wantError: `capability "Routre" is not declared`,
+		},
+		{
+			name: "capability rule not declared",
+			component: &Component{
+				CapabilityRules: []CapabilityRule{{Substring: "ingress", Capability: "Ingress"}},
+				Capabilities:    []CapabilityDefinition{{Name: "Router"}},
+			},
+			wantError: `capability "Ingress" is not declared`,
+		},
+		{
+			name:      "duplicate capability",
+			component: &Component{Capabilities: []CapabilityDefinition{{Name: "Router"}, {Name: "Router"}}},
+			wantError: "declared more than once",
+		},
+		{
+			name:      "capability without a name",
+			component: &Component{Capabilities: []CapabilityDefinition{{Description: "something"}}},
+			wantError: "without a name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.component.Name = "Test"
+			err := tt.component.Validate()
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("Validate() returned unexpected err: %+v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestExtractCapabilities(t *testing.T) {
+	tests := []struct {
+		name      string
+		component *Component
+		testName  string
+		want      []string
+	}{
+		{
+			name:      "defaults",
+			component: &Component{},
+			testName:  "[sig-network] clusteroperator/network alert/Foo [Feature:Router] [Capability:IPv6]",
+			want:      []string{"Router", "IPv6", "Operator", "Alerts"},
+		},
+		{
+			name: "additional fields and rules follow the defaults",
+			component: &Component{
+				CapabilityFields: []string{"Testpattern"},
+				CapabilityRules:  []CapabilityRule{{Substring: "ingress", Capability: "Ingress"}},
+			},
+			testName: "[sig-storage] ingress [Testpattern: Dynamic PV (default fs)] [Feature:CSI] alert/Foo",
+			want:     []string{"CSI", "Dynamic PV (default fs)", "Alerts", "Ingress"},
+		},
+		{
+			name: "defaults disabled",
+			component: &Component{
+				CapabilityFields:           []string{"Testpattern"},
+				DisableDefaultCapabilities: true,
+			},
+			testName: "[sig-storage] clusteroperator/storage [Testpattern: Dynamic PV (default fs)] [Feature:CSI]",
+			want:     []string{"Dynamic PV (default fs)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.component.ExtractCapabilities(&v1.TestInfo{Name: tt.testName})
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ExtractCapabilities() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
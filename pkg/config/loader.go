@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LoadComponents reads every YAML or JSON file under dir and returns the
+// component defined in each. Files are read in lexical order, so the result
+// is stable from run to run.
+func LoadComponents(dir string) ([]*Component, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var components []*Component
+	seen := make(map[string]string)
+	for _, file := range files {
+		component, err := LoadComponent(file)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[component.Name]; ok {
+			return nil, fmt.Errorf("component %q is defined in both %s and %s", component.Name, other, file)
+		}
+		seen[component.Name] = file
+		components = append(components, component)
+	}
+
+	return components, nil
+}
+
+// LoadComponent reads a single component definition from a YAML or JSON file.
+func LoadComponent(file string) (*Component, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var component Component
+	if err := yaml.UnmarshalStrict(data, &component); err != nil {
+		return nil, fmt.Errorf("could not parse component definition %s: %w", file, err)
+	}
+	if component.Name == "" {
+		return nil, fmt.Errorf("component definition %s has no name", file)
+	}
+	if component.DefaultJiraComponent == "" {
+		return nil, fmt.Errorf("component %q in %s has no defaultJiraComponent", component.Name, file)
+	}
+
+	return &component, nil
+}
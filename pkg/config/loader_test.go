@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+func TestLoadComponents(t *testing.T) {
+	tests := []struct {
+		name             string
+		files            map[string]string
+		wantError        string
+		wantComponents   []string
+		testInfo         *v1.TestInfo
+		wantCapabilities []string
+	}{
+		{
+			name: "loads yaml and json definitions",
+			files: map[string]string{
+				"router.yaml": `
+name: Router
+defaultJiraComponent: Networking / router
+operators:
+  - ingress
+matchers:
+  - sig: sig-network-edge
+    include:
+      - Feature:Router
+capabilityRules:
+  - substring: "ingress-to-"
+    capability: Disruption
+`,
+				"nested/dns.json": `{"name": "DNS", "defaultJiraComponent": "Networking / DNS"}`,
+			},
+			wantComponents:   []string{"DNS", "Router"},
+			testInfo:         &v1.TestInfo{Name: "[sig-network-edge][Feature:Router] ingress-to-foo"},
+			wantCapabilities: []string{"Router", "Disruption"},
+		},
+		{
+			name: "rejects unknown fields",
+			files: map[string]string{
+				"bad.yaml": "name: Bad\ndefaultJiraComponent: Bad\nmatcher: []\n",
+			},
+			wantError: "unknown field",
+		},
+		{
+			name: "requires a name",
+			files: map[string]string{
+				"bad.yaml": "defaultJiraComponent: Bad\n",
+			},
+			wantError: "has no name",
+		},
+		{
+			name: "rejects duplicate names",
+			files: map[string]string{
+				"a.yaml": "name: Dup\ndefaultJiraComponent: Dup\n",
+				"b.yaml": "name: Dup\ndefaultJiraComponent: Dup\n",
+			},
+			wantError: "is defined in both",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			components, err := LoadComponents(dir)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("LoadComponents() error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadComponents() returned unexpected err: %+v", err)
+			}
+
+			var names []string
+			for _, c := range components {
+				names = append(names, c.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantComponents) {
+				t.Errorf("LoadComponents() got components %v, want %v", names, tt.wantComponents)
+			}
+
+			if tt.testInfo == nil {
+				return
+			}
+			for _, c := range components {
+				ownership, err := c.IdentifyTest(tt.testInfo)
+				if err != nil {
+					t.Fatalf("IdentifyTest() returned unexpected err: %+v", err)
+				}
+				if ownership == nil {
+					continue
+				}
+				if !reflect.DeepEqual(ownership.Capabilities, tt.wantCapabilities) {
+					t.Errorf("IdentifyTest() gotCapabilities = %v, want %v", ownership.Capabilities, tt.wantCapabilities)
+				}
+				return
+			}
+			t.Errorf("IdentifyTest() no component claimed %q", tt.testInfo.Name)
+		})
+	}
+}
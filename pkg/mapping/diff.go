@@ -0,0 +1,177 @@
+package mapping
+
+import (
+	"sort"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+// Change describes a test whose mapping differs between two runs. Fields
+// prefixed with Old come from the earlier run, and New from the later one;
+// for added or removed tests, the side that doesn't exist is left empty.
+type Change struct {
+	Name            string   `json:"name"`
+	Suite           string   `json:"suite"`
+	OldID           string   `json:"old_id,omitempty"`
+	NewID           string   `json:"new_id,omitempty"`
+	OldComponent    string   `json:"old_component,omitempty"`
+	NewComponent    string   `json:"new_component,omitempty"`
+	OldCapabilities []string `json:"old_capabilities,omitempty"`
+	NewCapabilities []string `json:"new_capabilities,omitempty"`
+}
+
+// ComponentSummary counts the changes affecting a single component.
+// Reassignments are counted against both the losing and gaining component.
+type ComponentSummary struct {
+	Component           string `json:"component"`
+	Added               int    `json:"added"`
+	Removed             int    `json:"removed"`
+	ReassignedIn        int    `json:"reassigned_in"`
+	ReassignedOut       int    `json:"reassigned_out"`
+	CapabilitiesChanged int    `json:"capabilities_changed"`
+	IDsChanged          int    `json:"ids_changed"`
+}
+
+// Diff is the result of comparing two sets of test mappings. Tests are
+// matched by name and suite. A single test may appear in more than one of
+// Reassigned, CapabilitiesChanged and IDsChanged.
+type Diff struct {
+	Added               []Change           `json:"added"`
+	Removed             []Change           `json:"removed"`
+	Reassigned          []Change           `json:"reassigned"`
+	CapabilitiesChanged []Change           `json:"capabilities_changed"`
+	IDsChanged          []Change           `json:"ids_changed"`
+	Components          []ComponentSummary `json:"components"`
+}
+
+// Empty reports whether the two mappings were identical.
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Reassigned) == 0 &&
+		len(d.CapabilitiesChanged) == 0 && len(d.IDsChanged) == 0
+}
+
+type testKey struct {
+	name  string
+	suite string
+}
+
+// Compare returns the differences between an older and a newer set of test
+// mappings. Records marked StaffApprovedObsolete are not live mappings, and
+// are ignored.
+func Compare(oldMappings, newMappings []v1.TestOwnership) *Diff {
+	diff := &Diff{}
+	summaries := make(map[string]*ComponentSummary)
+	summary := func(component string) *ComponentSummary {
+		if s, ok := summaries[component]; ok {
+			return s
+		}
+		s := &ComponentSummary{Component: component}
+		summaries[component] = s
+		return s
+	}
+
+	oldByKey := make(map[testKey]*v1.TestOwnership, len(oldMappings))
+	for i := range oldMappings {
+		if oldMappings[i].StaffApprovedObsolete {
+			continue
+		}
+		oldByKey[testKey{oldMappings[i].Name, oldMappings[i].Suite}] = &oldMappings[i]
+	}
+	newByKey := make(map[testKey]*v1.TestOwnership, len(newMappings))
+	for i := range newMappings {
+		if newMappings[i].StaffApprovedObsolete {
+			continue
+		}
+		newByKey[testKey{newMappings[i].Name, newMappings[i].Suite}] = &newMappings[i]
+	}
+
+	for key, n := range newByKey {
+		o, ok := oldByKey[key]
+		if !ok {
+			diff.Added = append(diff.Added, Change{
+				Name:            n.Name,
+				Suite:           n.Suite,
+				NewID:           n.ID,
+				NewComponent:    n.Component,
+				NewCapabilities: n.Capabilities,
+			})
+			summary(n.Component).Added++
+			continue
+		}
+
+		change := Change{
+			Name:            n.Name,
+			Suite:           n.Suite,
+			OldID:           o.ID,
+			NewID:           n.ID,
+			OldComponent:    o.Component,
+			NewComponent:    n.Component,
+			OldCapabilities: o.Capabilities,
+			NewCapabilities: n.Capabilities,
+		}
+		if o.Component != n.Component {
+			diff.Reassigned = append(diff.Reassigned, change)
+			summary(o.Component).ReassignedOut++
+			summary(n.Component).ReassignedIn++
+		}
+		if !sameCapabilities(o.Capabilities, n.Capabilities) {
+			diff.CapabilitiesChanged = append(diff.CapabilitiesChanged, change)
+			summary(n.Component).CapabilitiesChanged++
+		}
+		if o.ID != n.ID {
+			diff.IDsChanged = append(diff.IDsChanged, change)
+			summary(n.Component).IDsChanged++
+		}
+	}
+
+	for key, o := range oldByKey {
+		if _, ok := newByKey[key]; ok {
+			continue
+		}
+		diff.Removed = append(diff.Removed, Change{
+			Name:            o.Name,
+			Suite:           o.Suite,
+			OldID:           o.ID,
+			OldComponent:    o.Component,
+			OldCapabilities: o.Capabilities,
+		})
+		summary(o.Component).Removed++
+	}
+
+	for _, changes := range [][]Change{diff.Added, diff.Removed, diff.Reassigned, diff.CapabilitiesChanged, diff.IDsChanged} {
+		sortChanges(changes)
+	}
+	for _, s := range summaries {
+		diff.Components = append(diff.Components, *s)
+	}
+	sort.Slice(diff.Components, func(i, j int) bool {
+		return diff.Components[i].Component < diff.Components[j].Component
+	})
+
+	return diff
+}
+
+func sortChanges(changes []Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Suite < changes[j].Suite
+	})
+}
+
+// sameCapabilities compares two capability lists, ignoring order and duplicates.
+func sameCapabilities(a, b []string) bool {
+	set := make(map[string]bool, len(a))
+	for _, c := range a {
+		set[c] = true
+	}
+	other := make(map[string]bool, len(b))
+	for _, c := range b {
+		if !set[c] {
+			return false
+		}
+		other[c] = true
+	}
+	return len(set) == len(other)
+}
@@ -0,0 +1,77 @@
+package mapping
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+func TestCompare(t *testing.T) {
+	oldMappings := []v1.TestOwnership{
+		{ID: "1", Name: "unchanged", Suite: "s", Component: "A", Capabilities: []string{"x", "y"}},
+		{ID: "2", Name: "moved", Suite: "s", Component: "A", Capabilities: []string{"x"}},
+		{ID: "3", Name: "removed", Suite: "s", Component: "B", Capabilities: []string{"x"}},
+		{ID: "4", Name: "capabilities", Suite: "s", Component: "B", Capabilities: []string{"x"}},
+		{ID: "5", Name: "renamed-id", Suite: "s", Component: "B", Capabilities: []string{"x"}},
+	}
+	newMappings := []v1.TestOwnership{
+		{ID: "1", Name: "unchanged", Suite: "s", Component: "A", Capabilities: []string{"y", "x"}},
+		{ID: "2", Name: "moved", Suite: "s", Component: "B", Capabilities: []string{"x"}},
+		{ID: "4", Name: "capabilities", Suite: "s", Component: "B", Capabilities: []string{"z"}},
+		{ID: "6", Name: "renamed-id", Suite: "s", Component: "B", Capabilities: []string{"x"}},
+		{ID: "7", Name: "added", Suite: "s", Component: "C", Capabilities: []string{"x"}},
+	}
+
+	diff := Compare(oldMappings, newMappings)
+
+	names := func(changes []Change) (result []string) {
+		for _, c := range changes {
+			result = append(result, c.Name)
+		}
+		return result
+	}
+	for _, tc := range []struct {
+		name string
+		got  []Change
+		want []string
+	}{
+		{"added", diff.Added, []string{"added"}},
+		{"removed", diff.Removed, []string{"removed"}},
+		{"reassigned", diff.Reassigned, []string{"moved"}},
+		{"capabilities changed", diff.CapabilitiesChanged, []string{"capabilities"}},
+		{"IDs changed", diff.IDsChanged, []string{"renamed-id"}},
+	} {
+		if got := names(tc.got); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Compare() %s = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	wantSummaries := []ComponentSummary{
+		{Component: "A", ReassignedOut: 1},
+		{Component: "B", Removed: 1, ReassignedIn: 1, CapabilitiesChanged: 1, IDsChanged: 1},
+		{Component: "C", Added: 1},
+	}
+	if !reflect.DeepEqual(diff.Components, wantSummaries) {
+		t.Errorf("Compare() components = %+v, want %+v", diff.Components, wantSummaries)
+	}
+
+	for _, format := range []string{FormatText, FormatJSON, FormatMarkdown} {
+		var buf bytes.Buffer
+		if err := WriteReport(&buf, diff, format); err != nil {
+			t.Fatalf("WriteReport(%s) returned unexpected err: %+v", format, err)
+		}
+		if !strings.Contains(buf.String(), "moved") {
+			t.Errorf("WriteReport(%s) output does not mention the reassigned test:\n%s", format, buf.String())
+		}
+	}
+}
+
+func TestCompareIdentical(t *testing.T) {
+	mappings := []v1.TestOwnership{{ID: "1", Name: "a", Component: "A", Capabilities: []string{"x"}}}
+	if diff := Compare(mappings, mappings); !diff.Empty() {
+		t.Errorf("Compare() of identical mappings = %+v, want no changes", diff)
+	}
+}
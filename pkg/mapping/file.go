@@ -0,0 +1,57 @@
+package mapping
+
+import (
+	"encoding/json"
+	"os"
+
+	"cloud.google.com/go/civil"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+// LoadFile reads a list of test ownership records, such as mapping.json,
+// from a file.
+func LoadFile(filename string) ([]v1.TestOwnership, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []v1.TestOwnership
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, err
+	}
+
+	return mappings, nil
+}
+
+// Latest returns only the records belonging to the most recent complete
+// snapshot, i.e. those with the newest CreatedAt among the snapshots with at
+// least as many records as they were pushed with. Records without a
+// SnapshotSize predate it, and are assumed complete.
+func Latest(mappings []v1.TestOwnership) []v1.TestOwnership {
+	rows := make(map[civil.DateTime]int)
+	expected := make(map[civil.DateTime]int)
+	for i := range mappings {
+		rows[mappings[i].CreatedAt]++
+		if mappings[i].SnapshotSize > expected[mappings[i].CreatedAt] {
+			expected[mappings[i].CreatedAt] = mappings[i].SnapshotSize
+		}
+	}
+
+	var newest civil.DateTime
+	for createdAt := range rows {
+		if rows[createdAt] >= expected[createdAt] && createdAt.After(newest) {
+			newest = createdAt
+		}
+	}
+
+	var latest []v1.TestOwnership
+	for i := range mappings {
+		if mappings[i].CreatedAt == newest {
+			latest = append(latest, mappings[i])
+		}
+	}
+
+	return latest
+}
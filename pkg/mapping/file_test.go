@@ -0,0 +1,64 @@
+package mapping
+
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/civil"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+func TestLatest(t *testing.T) {
+	older := civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 1}}
+	newer := civil.DateTime{Date: civil.Date{Year: 2023, Month: 5, Day: 2}}
+
+	tests := []struct {
+		name     string
+		mappings []v1.TestOwnership
+		want     []string
+	}{
+		{
+			name: "newest snapshot",
+			mappings: []v1.TestOwnership{
+				{Name: "a", CreatedAt: older, SnapshotSize: 1},
+				{Name: "b", CreatedAt: newer, SnapshotSize: 2},
+				{Name: "c", CreatedAt: newer, SnapshotSize: 2},
+			},
+			want: []string{"b", "c"},
+		},
+		{
+			name: "partially pushed newest snapshot",
+			mappings: []v1.TestOwnership{
+				{Name: "a", CreatedAt: older, SnapshotSize: 1},
+				{Name: "b", CreatedAt: newer, SnapshotSize: 2},
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "snapshots without a size",
+			mappings: []v1.TestOwnership{
+				{Name: "a", CreatedAt: older},
+				{Name: "b", CreatedAt: newer},
+			},
+			want: []string{"b"},
+		},
+		{
+			name: "no complete snapshot",
+			mappings: []v1.TestOwnership{
+				{Name: "a", CreatedAt: newer, SnapshotSize: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range Latest(tt.mappings) {
+				got = append(got, m.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Latest() returned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package mapping
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+// FingerprintTest returns a hash of everything a component may look at when
+// identifying the test: its name, suite and metadata.
+func FingerprintTest(test *v1.TestInfo) string {
+	// Marshaling a TestInfo can't fail, and sorts the annotations by key.
+	data, _ := json.Marshal(test)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// CarryOver finds the tests whose ownership can be reused from the previous
+// snapshot: those with a previous record from the same registry, for the
+// same test fingerprint. It returns copies of those records, and the
+// remaining tests, which need to be identified. Records marked
+// StaffApprovedObsolete are never carried over.
+func CarryOver(previous []v1.TestOwnership, tests []v1.TestInfo, registryFingerprint string) (carried []v1.TestOwnership, remaining []v1.TestInfo) {
+	byKey := make(map[testKey]*v1.TestOwnership, len(previous))
+	for i := range previous {
+		p := &previous[i]
+		if p.StaffApprovedObsolete || p.TestFingerprint == "" || p.RegistryFingerprint != registryFingerprint {
+			continue
+		}
+		byKey[testKey{p.Name, p.Suite}] = p
+	}
+
+	for i := range tests {
+		p, ok := byKey[testKey{tests[i].Name, tests[i].Suite}]
+		if ok && p.TestFingerprint == FingerprintTest(&tests[i]) {
+			carried = append(carried, *p)
+			continue
+		}
+		remaining = append(remaining, tests[i])
+	}
+
+	return carried, remaining
+}
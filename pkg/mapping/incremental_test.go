@@ -0,0 +1,77 @@
+package mapping
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+func TestCarryOver(t *testing.T) {
+	unchanged := v1.TestInfo{Name: "unchanged", Suite: "s"}
+	changed := v1.TestInfo{Name: "changed", Suite: "s", FilePath: "new.go"}
+	oldChanged := v1.TestInfo{Name: "changed", Suite: "s", FilePath: "old.go"}
+	otherSuite := v1.TestInfo{Name: "unchanged", Suite: "other"}
+	obsolete := v1.TestInfo{Name: "obsolete", Suite: "s"}
+	unfingerprinted := v1.TestInfo{Name: "unfingerprinted", Suite: "s"}
+	added := v1.TestInfo{Name: "added", Suite: "s"}
+
+	previous := []v1.TestOwnership{
+		{ID: "1", Name: "unchanged", Suite: "s", Component: "A", TestFingerprint: FingerprintTest(&unchanged), RegistryFingerprint: "r1"},
+		{ID: "2", Name: "changed", Suite: "s", Component: "A", TestFingerprint: FingerprintTest(&oldChanged), RegistryFingerprint: "r1"},
+		{ID: "3", Name: "obsolete", Suite: "s", Component: "A", TestFingerprint: FingerprintTest(&obsolete), RegistryFingerprint: "r1", StaffApprovedObsolete: true},
+		{ID: "4", Name: "unfingerprinted", Suite: "s", Component: "A"},
+	}
+	tests := []v1.TestInfo{unchanged, changed, otherSuite, obsolete, unfingerprinted, added}
+
+	cases := []struct {
+		name          string
+		registry      string
+		wantCarried   []string
+		wantRemaining []string
+	}{
+		{
+			name:          "same registry",
+			registry:      "r1",
+			wantCarried:   []string{"1"},
+			wantRemaining: []string{"changed", "unchanged", "obsolete", "unfingerprinted", "added"},
+		},
+		{
+			name:          "changed registry",
+			registry:      "r2",
+			wantRemaining: []string{"unchanged", "changed", "unchanged", "obsolete", "unfingerprinted", "added"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			carried, remaining := CarryOver(previous, tests, tt.registry)
+
+			var carriedIDs, remainingNames []string
+			for _, c := range carried {
+				carriedIDs = append(carriedIDs, c.ID)
+			}
+			for _, r := range remaining {
+				remainingNames = append(remainingNames, r.Name)
+			}
+			if !reflect.DeepEqual(carriedIDs, tt.wantCarried) {
+				t.Errorf("CarryOver() carried = %v, want %v", carriedIDs, tt.wantCarried)
+			}
+			if !reflect.DeepEqual(remainingNames, tt.wantRemaining) {
+				t.Errorf("CarryOver() remaining = %v, want %v", remainingNames, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestFingerprintTest(t *testing.T) {
+	a := v1.TestInfo{Name: "test", Suite: "s", Annotations: map[string]string{"a": "1", "b": "2"}}
+	b := v1.TestInfo{Name: "test", Suite: "s", Annotations: map[string]string{"b": "2", "a": "1"}}
+	if FingerprintTest(&a) != FingerprintTest(&b) {
+		t.Errorf("FingerprintTest() differs for equal tests")
+	}
+
+	b.Labels = []string{"label"}
+	if FingerprintTest(&a) == FingerprintTest(&b) {
+		t.Errorf("FingerprintTest() is the same for tests with different labels")
+	}
+}
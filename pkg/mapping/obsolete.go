@@ -0,0 +1,124 @@
+package mapping
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+// ObsoleteApproval records a staff engineer's approval for a previously
+// mapped test to lose its ownership, either because the test no longer
+// exists or because its stable ID changed. Approvals are keyed by the
+// test's previous ID; Name and Suite are informational.
+type ObsoleteApproval struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Suite    string `json:"suite,omitempty"`
+	Approver string `json:"approver"`
+	Reason   string `json:"reason"`
+}
+
+// LoadObsoleteApprovals reads a YAML or JSON list of approvals. A missing file
+// is treated as an empty list.
+func LoadObsoleteApprovals(filename string) (map[string]ObsoleteApproval, error) {
+	approvals := make(map[string]ObsoleteApproval)
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return approvals, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var list []ObsoleteApproval
+	if err := yaml.UnmarshalStrict(data, &list); err != nil {
+		return nil, fmt.Errorf("could not parse obsolete approvals %s: %w", filename, err)
+	}
+	for _, approval := range list {
+		switch {
+		case approval.ID == "":
+			return nil, fmt.Errorf("obsolete approval for %q in %s has no id", approval.Name, filename)
+		case approval.Approver == "":
+			return nil, fmt.Errorf("obsolete approval %s in %s has no approver", approval.ID, filename)
+		case approval.Reason == "":
+			return nil, fmt.Errorf("obsolete approval %s in %s has no reason", approval.ID, filename)
+		}
+		if _, ok := approvals[approval.ID]; ok {
+			return nil, fmt.Errorf("obsolete approval %s is listed more than once in %s", approval.ID, filename)
+		}
+		approvals[approval.ID] = approval
+	}
+
+	return approvals, nil
+}
+
+// ObsoleteCheck is the result of comparing new mappings to previous ones.
+type ObsoleteCheck struct {
+	// Approved are the previous records of tests that lost their ownership
+	// with approval, marked StaffApprovedObsolete. They should be emitted
+	// along with the new mappings.
+	Approved []v1.TestOwnership
+
+	// Unapproved are the previous records of tests that lost their ownership
+	// without approval.
+	Unapproved []v1.TestOwnership
+
+	// Unused are approvals that did not match any lost test.
+	Unused []ObsoleteApproval
+}
+
+// CheckObsolete finds the previously mapped tests whose IDs are missing from
+// the new mappings, either because the test disappeared or because its
+// stable ID changed. Records that were already marked StaffApprovedObsolete
+// stay approved.
+func CheckObsolete(previous, current []v1.TestOwnership, approvals map[string]ObsoleteApproval) *ObsoleteCheck {
+	currentIDs := make(map[string]bool, len(current))
+	for i := range current {
+		currentIDs[current[i].ID] = true
+	}
+
+	check := &ObsoleteCheck{}
+	used := make(map[string]bool)
+	seen := make(map[string]bool)
+	for i := range previous {
+		record := previous[i]
+		if currentIDs[record.ID] || seen[record.ID] {
+			continue
+		}
+		seen[record.ID] = true
+
+		if _, ok := approvals[record.ID]; ok || record.StaffApprovedObsolete {
+			used[record.ID] = true
+			record.StaffApprovedObsolete = true
+			check.Approved = append(check.Approved, record)
+			continue
+		}
+		check.Unapproved = append(check.Unapproved, record)
+	}
+
+	for id, approval := range approvals {
+		if !used[id] {
+			check.Unused = append(check.Unused, approval)
+		}
+	}
+
+	sortOwnerships(check.Approved)
+	sortOwnerships(check.Unapproved)
+	sort.Slice(check.Unused, func(i, j int) bool {
+		return check.Unused[i].ID < check.Unused[j].ID
+	})
+
+	return check
+}
+
+func sortOwnerships(ownerships []v1.TestOwnership) {
+	sort.Slice(ownerships, func(i, j int) bool {
+		if ownerships[i].Name != ownerships[j].Name {
+			return ownerships[i].Name < ownerships[j].Name
+		}
+		return ownerships[i].Suite < ownerships[j].Suite
+	})
+}
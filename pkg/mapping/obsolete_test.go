@@ -0,0 +1,107 @@
+package mapping
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+func TestCheckObsolete(t *testing.T) {
+	previous := []v1.TestOwnership{
+		{ID: "kept", Name: "kept"},
+		{ID: "removed-approved", Name: "removed-approved"},
+		{ID: "removed", Name: "removed"},
+		{ID: "old-id", Name: "renamed"},
+		{ID: "already-obsolete", Name: "already-obsolete", StaffApprovedObsolete: true},
+	}
+	current := []v1.TestOwnership{
+		{ID: "kept", Name: "kept"},
+		{ID: "new-id", Name: "renamed"},
+	}
+	approvals := map[string]ObsoleteApproval{
+		"removed-approved": {ID: "removed-approved", Approver: "someone", Reason: "test deleted"},
+		"stale":            {ID: "stale", Approver: "someone", Reason: "no longer needed"},
+	}
+
+	check := CheckObsolete(previous, current, approvals)
+
+	ids := func(ownerships []v1.TestOwnership) (result []string) {
+		for _, o := range ownerships {
+			result = append(result, o.ID)
+			if o.StaffApprovedObsolete != (o.ID != "removed" && o.ID != "old-id") {
+				t.Errorf("CheckObsolete() record %s has StaffApprovedObsolete = %v", o.ID, o.StaffApprovedObsolete)
+			}
+		}
+		return result
+	}
+	if got, want := ids(check.Approved), []string{"already-obsolete", "removed-approved"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckObsolete() approved = %v, want %v", got, want)
+	}
+	if got, want := ids(check.Unapproved), []string{"removed", "old-id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckObsolete() unapproved = %v, want %v", got, want)
+	}
+	if len(check.Unused) != 1 || check.Unused[0].ID != "stale" {
+		t.Errorf("CheckObsolete() unused = %v, want [stale]", check.Unused)
+	}
+}
+
+func TestLoadObsoleteApprovals(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantIDs   []string
+		wantError string
+	}{
+		{
+			name: "loads approvals",
+			content: `
+- id: abc
+  name: "[sig-foo] removed test"
+  approver: someone
+  reason: test was deleted upstream
+`,
+			wantIDs: []string{"abc"},
+		},
+		{
+			name:      "requires an approver",
+			content:   "- id: abc\n  reason: because\n",
+			wantError: "has no approver",
+		},
+		{
+			name:      "requires a reason",
+			content:   "- id: abc\n  approver: someone\n",
+			wantError: "has no reason",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "approvals.yaml")
+			if err := os.WriteFile(file, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			approvals, err := LoadObsoleteApprovals(file)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("LoadObsoleteApprovals() error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadObsoleteApprovals() returned unexpected err: %+v", err)
+			}
+			for _, id := range tt.wantIDs {
+				if _, ok := approvals[id]; !ok {
+					t.Errorf("LoadObsoleteApprovals() missing approval %s", id)
+				}
+			}
+		})
+	}
+
+	if approvals, err := LoadObsoleteApprovals(filepath.Join(t.TempDir(), "missing.yaml")); err != nil || len(approvals) != 0 {
+		t.Errorf("LoadObsoleteApprovals() of a missing file = %v, %v; want no approvals", approvals, err)
+	}
+}
@@ -0,0 +1,152 @@
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+)
+
+// WriteReport renders the diff in the given format: text, json or markdown.
+func WriteReport(w io.Writer, diff *Diff, format string) error {
+	switch format {
+	case FormatText:
+		return writeText(w, diff)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	case FormatMarkdown:
+		return writeMarkdown(w, diff)
+	default:
+		return fmt.Errorf("unknown format %q, must be one of: %s, %s, %s", format, FormatText, FormatJSON, FormatMarkdown)
+	}
+}
+
+func writeText(w io.Writer, diff *Diff) error {
+	if diff.Empty() {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPONENT\tADDED\tREMOVED\tIN\tOUT\tCAPABILITIES\tIDS")
+	for _, s := range diff.Components {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\n", s.Component, s.Added, s.Removed,
+			s.ReassignedIn, s.ReassignedOut, s.CapabilitiesChanged, s.IDsChanged)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		title   string
+		changes []Change
+		detail  func(Change) string
+	}{
+		{"Added", diff.Added, func(c Change) string { return c.NewComponent }},
+		{"Removed", diff.Removed, func(c Change) string { return c.OldComponent }},
+		{"Reassigned", diff.Reassigned, func(c Change) string {
+			return fmt.Sprintf("%s -> %s", c.OldComponent, c.NewComponent)
+		}},
+		{"Capabilities changed", diff.CapabilitiesChanged, func(c Change) string {
+			return fmt.Sprintf("%v -> %v", c.OldCapabilities, c.NewCapabilities)
+		}},
+		{"IDs changed", diff.IDsChanged, func(c Change) string {
+			return fmt.Sprintf("%s -> %s", c.OldID, c.NewID)
+		}},
+	}
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s (%d):\n", section.title, len(section.changes))
+		for _, c := range section.changes {
+			fmt.Fprintf(&b, "  %s: %s\n", testLabel(c), section.detail(c))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdown(w io.Writer, diff *Diff) error {
+	var b strings.Builder
+	b.WriteString("## Test mapping changes\n\n")
+	if diff.Empty() {
+		b.WriteString("No changes.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	fmt.Fprintf(&b, "%d added, %d removed, %d reassigned, %d with capability changes, %d with ID changes.\n\n",
+		len(diff.Added), len(diff.Removed), len(diff.Reassigned), len(diff.CapabilitiesChanged), len(diff.IDsChanged))
+
+	b.WriteString("| Component | Added | Removed | Reassigned in | Reassigned out | Capabilities changed | IDs changed |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, s := range diff.Components {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d | %d |\n", markdownEscape(s.Component), s.Added, s.Removed,
+			s.ReassignedIn, s.ReassignedOut, s.CapabilitiesChanged, s.IDsChanged)
+	}
+
+	sections := []struct {
+		title   string
+		changes []Change
+		header  string
+		row     func(Change) string
+	}{
+		{"Added", diff.Added, "| Test | Component |", func(c Change) string {
+			return fmt.Sprintf("| %s | %s |", markdownEscape(testLabel(c)), markdownEscape(c.NewComponent))
+		}},
+		{"Removed", diff.Removed, "| Test | Component |", func(c Change) string {
+			return fmt.Sprintf("| %s | %s |", markdownEscape(testLabel(c)), markdownEscape(c.OldComponent))
+		}},
+		{"Reassigned", diff.Reassigned, "| Test | From | To |", func(c Change) string {
+			return fmt.Sprintf("| %s | %s | %s |", markdownEscape(testLabel(c)),
+				markdownEscape(c.OldComponent), markdownEscape(c.NewComponent))
+		}},
+		{"Capabilities changed", diff.CapabilitiesChanged, "| Test | From | To |", func(c Change) string {
+			return fmt.Sprintf("| %s | %s | %s |", markdownEscape(testLabel(c)),
+				markdownEscape(strings.Join(c.OldCapabilities, ", ")), markdownEscape(strings.Join(c.NewCapabilities, ", ")))
+		}},
+		{"IDs changed", diff.IDsChanged, "| Test | From | To |", func(c Change) string {
+			return fmt.Sprintf("| %s | `%s` | `%s` |", markdownEscape(testLabel(c)), c.OldID, c.NewID)
+		}},
+	}
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n<details>\n<summary>%s (%d)</summary>\n\n", section.title, len(section.changes))
+		b.WriteString(section.header + "\n")
+		b.WriteString(strings.Repeat("|---", strings.Count(section.header, "|")-1) + "|\n")
+		for _, c := range section.changes {
+			b.WriteString(section.row(c) + "\n")
+		}
+		b.WriteString("\n</details>\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func testLabel(c Change) string {
+	if c.Suite == "" {
+		return c.Name
+	}
+	return fmt.Sprintf("%s (%s)", c.Name, c.Suite)
+}
+
+var markdownReplacer = strings.NewReplacer("|", `\|`, "\n", " ", "<", "&lt;", ">", "&gt;")
+
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"sort"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+)
+
+// CapabilityCataloger is implemented by components that declare the
+// capabilities their tests may be assigned, such as those built on
+// config.Component.
+type CapabilityCataloger interface {
+	CapabilityCatalog() []config.CapabilityDefinition
+}
+
+// ComponentCapabilities is a component's entry in the capability catalog.
+type ComponentCapabilities struct {
+	Component      string                        `json:"component"`
+	JiraComponents []string                      `json:"jiraComponents"`
+	Capabilities   []config.CapabilityDefinition `json:"capabilities"`
+}
+
+// CapabilityCatalog returns every registered component, in name order, with
+// the capabilities it declares. Components without a catalog have no
+// capabilities listed.
+func (r *Registry) CapabilityCatalog() []ComponentCapabilities {
+	var names []string
+	for name := range r.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	catalog := make([]ComponentCapabilities, 0, len(names))
+	for _, name := range names {
+		component := r.Components[name]
+		entry := ComponentCapabilities{
+			Component:      name,
+			Capabilities:   []config.CapabilityDefinition{},
+			JiraComponents: []string{},
+		}
+		for _, jira := range component.JiraComponents() {
+			if jira != "" && !contains(entry.JiraComponents, jira) {
+				entry.JiraComponents = append(entry.JiraComponents, jira)
+			}
+		}
+		if c, ok := component.(CapabilityCataloger); ok {
+			entry.Capabilities = append(entry.Capabilities, c.CapabilityCatalog()...)
+		}
+		catalog = append(catalog, entry)
+	}
+
+	return catalog
+}
+
+// UndeclaredCapabilities returns the capabilities in the list that the named
+// component doesn't declare. Components without a catalog accept any
+// capability, and every component accepts the allowed ones, such as the
+// default capability assigned to tests without any.
+func (r *Registry) UndeclaredCapabilities(name string, capabilities []string, allowed ...string) []string {
+	c, ok := r.Components[name].(CapabilityCataloger)
+	if !ok {
+		return nil
+	}
+	catalog := c.CapabilityCatalog()
+	if len(catalog) == 0 {
+		return nil
+	}
+
+	declared := make(map[string]bool, len(catalog)+len(allowed))
+	for _, capability := range catalog {
+		declared[capability.Name] = true
+	}
+	for _, capability := range allowed {
+		declared[capability] = true
+	}
+
+	var undeclared []string
+	for _, capability := range capabilities {
+		if !declared[capability] {
+			undeclared = append(undeclared, capability)
+		}
+	}
+	return undeclared
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
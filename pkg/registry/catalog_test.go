@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+)
+
+func newCatalogRegistry() *Registry {
+	var r Registry
+	r.Register("Networking / router", &config.Component{
+		Name:                 "Networking / router",
+		DefaultJiraComponent: "Networking / router",
+		Matchers:             []config.ComponentMatcher{{SIG: "sig-network-edge"}, {Include: []string{"dns"}, JiraComponent: "Networking / DNS"}},
+		Capabilities: []config.CapabilityDefinition{
+			{Name: "Router", Description: "Ingress routing"},
+			{Name: "Disruption"},
+		},
+	})
+	r.Register("Etcd", &config.Component{
+		Name:                 "Etcd",
+		DefaultJiraComponent: "Etcd",
+	})
+	return &r
+}
+
+func TestCapabilityCatalog(t *testing.T) {
+	want := []ComponentCapabilities{
+		{
+			Component:      "Etcd",
+			JiraComponents: []string{"Etcd"},
+			Capabilities:   []config.CapabilityDefinition{},
+		},
+		{
+			Component:      "Networking / router",
+			JiraComponents: []string{"Networking / router", "Networking / DNS"},
+			Capabilities: []config.CapabilityDefinition{
+				{Name: "Router", Description: "Ingress routing"},
+				{Name: "Disruption"},
+			},
+		},
+	}
+	if got := newCatalogRegistry().CapabilityCatalog(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CapabilityCatalog() got = %+v, want %+v", got, want)
+	}
+}
+
+func TestUndeclaredCapabilities(t *testing.T) {
+	tests := []struct {
+		name         string
+		component    string
+		capabilities []string
+		want         []string
+	}{
+		{
+			name:         "declared capabilities",
+			component:    "Networking / router",
+			capabilities: []string{"Router", "Disruption", "Other"},
+		},
+		{
+			name:         "undeclared capabilities",
+			component:    "Networking / router",
+			capabilities: []string{"Router", "Routre", "Ingress"},
+			want:         []string{"Routre", "Ingress"},
+		},
+		{
+			name:         "component without a catalog accepts anything",
+			component:    "Etcd",
+			capabilities: []string{"Anything"},
+		},
+		{
+			name:         "unknown component",
+			component:    "Unknown",
+			capabilities: []string{"Anything"},
+		},
+	}
+	reg := newCatalogRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reg.UndeclaredCapabilities(tt.component, tt.capabilities, "Other"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UndeclaredCapabilities() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
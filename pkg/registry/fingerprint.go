@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+	"github.com/openshift-eng/ci-test-mapping/pkg/version"
+)
+
+// Fingerprint returns a hash of the registry's definition: every component's
+// name and configuration, along with the default capability extraction, and
+// the version and commit of the build. If it's unchanged, tests are
+// identified the same way as before.
+//
+// The matching code, including components with custom Go logic, is only
+// covered by the build's commit. For builds whose commit doesn't identify
+// their code, unknown or dirty, the fingerprint doesn't change with the code,
+// so map doesn't carry over ownership with them (see version.Identifies).
+func (r *Registry) Fingerprint() (string, error) {
+	names := make([]string, 0, len(r.Components))
+	for name := range r.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type entry struct {
+		Name           string            `json:"name"`
+		Type           string            `json:"type"`
+		JiraComponents []string          `json:"jiraComponents"`
+		Config         *config.Component `json:"config,omitempty"`
+	}
+	toolVersion, toolCommit := version.Get()
+	definition := struct {
+		Version          string                  `json:"version"`
+		Commit           string                  `json:"commit"`
+		CapabilityFields []string                `json:"capabilityFields"`
+		CapabilityRules  []config.CapabilityRule `json:"capabilityRules"`
+		Components       []entry                 `json:"components"`
+	}{
+		Version:          toolVersion,
+		Commit:           toolCommit,
+		CapabilityFields: config.DefaultCapabilityFields,
+		CapabilityRules:  config.DefaultCapabilityRules,
+	}
+
+	for _, name := range names {
+		component := r.Components[name]
+		e := entry{
+			Name:           name,
+			Type:           fmt.Sprintf("%T", component),
+			JiraComponents: component.JiraComponents(),
+		}
+		if configurable, ok := component.(interface{ Config() *config.Component }); ok {
+			e.Config = configurable.Config()
+		}
+		definition.Components = append(definition.Components, e)
+	}
+
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return "", fmt.Errorf("could not fingerprint registry: %w", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
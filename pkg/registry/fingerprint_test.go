@@ -0,0 +1,44 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+	"github.com/openshift-eng/ci-test-mapping/pkg/version"
+)
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := func(r *Registry) string {
+		t.Helper()
+		f, err := r.Fingerprint()
+		if err != nil {
+			t.Fatalf("Fingerprint() returned unexpected err: %+v", err)
+		}
+		return f
+	}
+
+	original := fingerprint(newCatalogRegistry())
+	if again := fingerprint(newCatalogRegistry()); again != original {
+		t.Errorf("Fingerprint() got = %s for the same registry, want %s", again, original)
+	}
+
+	changed := newCatalogRegistry()
+	changed.Components["Etcd"].(*config.Component).Matchers = []config.ComponentMatcher{{SIG: "sig-etcd"}}
+	if fingerprint(changed) == original {
+		t.Errorf("Fingerprint() didn't change when a matcher was added")
+	}
+
+	// The matching code isn't hashed, so a new build invalidates it
+	version.Commit = "abc123"
+	defer func() { version.Commit = "" }()
+	if fingerprint(newCatalogRegistry()) == original {
+		t.Errorf("Fingerprint() didn't change when the build's commit changed")
+	}
+	version.Commit = ""
+
+	added := newCatalogRegistry()
+	added.Register("Storage", &config.Component{Name: "Storage"})
+	if fingerprint(added) == original {
+		t.Errorf("Fingerprint() didn't change when a component was added")
+	}
+}
@@ -0,0 +1,32 @@
+package registry
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+)
+
+// LoadDirectory registers every component defined in the YAML or JSON files
+// under dir. A definition may not reuse the name of a component that is
+// already registered, including one written in Go.
+func (r *Registry) LoadDirectory(dir string) error {
+	components, err := config.LoadComponents(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range components {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if _, ok := r.Components[c.Name]; ok {
+			return fmt.Errorf("component %q from %s is already registered", c.Name, dir)
+		}
+		r.Register(c.Name, c)
+	}
+	log.Debugf("loaded %d components from %s", len(components), dir)
+
+	return nil
+}
@@ -1,6 +1,11 @@
 package registry
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
 	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
 	"github.com/openshift-eng/ci-test-mapping/pkg/components/apiserverauth"
 	"github.com/openshift-eng/ci-test-mapping/pkg/components/awsloadbalanceroperator"
@@ -182,6 +187,12 @@ import (
 
 type Registry struct {
 	Components map[string]v1.Component
+
+	// jiraOwners maps every Jira component to the name of the component
+	// owning it. It's built by the first lookup, and reset by Register and
+	// Deregister.
+	jiraOwnersLock sync.RWMutex
+	jiraOwners     map[string]string
 }
 
 func NewComponentRegistry() *Registry {
@@ -374,20 +385,102 @@ func (r *Registry) Register(name string, component v1.Component) {
 	}
 
 	r.Components[name] = component
+	r.resetJiraOwners()
 }
 
+// GetForJiraComponent returns the component owning the named Jira component,
+// or nil if there's none. See LookupJiraComponent.
 func (r *Registry) GetForJiraComponent(name string) v1.Component {
-	for _, c := range r.Components {
-		for _, j := range c.JiraComponents() {
-			if j == name {
-				return c
+	_, c := r.LookupJiraComponent(name)
+	return c
+}
+
+// LookupJiraComponent returns the name of the component owning the named Jira
+// component, along with the component itself. A component whose default
+// (first) Jira component matches is preferred over one that only routes some
+// tests to it; ties are broken by component name. Owners are looked up in a
+// map built once, so components must only be added or removed through
+// Register and Deregister.
+func (r *Registry) LookupJiraComponent(name string) (string, v1.Component) {
+	owner, ok := r.jiraComponentOwners()[name]
+	if !ok {
+		return "", nil
+	}
+	return owner, r.Components[owner]
+}
+
+// jiraComponentOwners returns the owner of every Jira component, building
+// the map if no lookup has since the registry last changed.
+func (r *Registry) jiraComponentOwners() map[string]string {
+	r.jiraOwnersLock.RLock()
+	owners := r.jiraOwners
+	r.jiraOwnersLock.RUnlock()
+	if owners != nil {
+		return owners
+	}
+
+	r.jiraOwnersLock.Lock()
+	defer r.jiraOwnersLock.Unlock()
+	if r.jiraOwners != nil {
+		return r.jiraOwners
+	}
+
+	names := make([]string, 0, len(r.Components))
+	for n := range r.Components {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	owners = make(map[string]string)
+	isDefault := make(map[string]bool)
+	for _, n := range names {
+		for i, j := range r.Components[n].JiraComponents() {
+			if isDefault[j] {
+				continue
+			}
+			if _, ok := owners[j]; !ok || i == 0 {
+				owners[j] = n
+				isDefault[j] = i == 0
 			}
 		}
 	}
+	r.jiraOwners = owners
+	return owners
+}
 
-	return nil
+func (r *Registry) resetJiraOwners() {
+	r.jiraOwnersLock.Lock()
+	defer r.jiraOwnersLock.Unlock()
+	r.jiraOwners = nil
 }
 
 func (r *Registry) Deregister(name string) {
 	delete(r.Components, name)
+	r.resetJiraOwners()
+}
+
+// Validate checks every registered component that supports validation, such
+// as those built on config.Component, and reports all problems found.
+func (r *Registry) Validate() error {
+	var names []string
+	for name := range r.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		v, ok := r.Components[name].(interface{ Validate() error })
+		if !ok {
+			continue
+		}
+		if err := v.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid component configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
 }
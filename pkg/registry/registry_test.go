@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+)
+
+func TestLookupJiraComponent(t *testing.T) {
+	r := newCatalogRegistry()
+	r.Register("Aggregated", &config.Component{
+		Name:                 "Aggregated",
+		DefaultJiraComponent: "Aggregated",
+		Matchers:             []config.ComponentMatcher{{Include: []string{"etcd"}, JiraComponent: "Etcd"}},
+	})
+
+	tests := []struct {
+		name string
+		jira string
+		want string
+	}{
+		{name: "default Jira component", jira: "Networking / router", want: "Networking / router"},
+		{name: "default preferred over a matcher's", jira: "Etcd", want: "Etcd"},
+		{name: "matcher Jira component", jira: "Networking / DNS", want: "Networking / router"},
+		{name: "unknown Jira component", jira: "Unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, component := r.LookupJiraComponent(tt.jira)
+			if got != tt.want || (component == nil) != (tt.want == "") {
+				t.Errorf("LookupJiraComponent(%q) = %q, %v, want %q", tt.jira, got, component, tt.want)
+			}
+		})
+	}
+
+	// Changing the registry rebuilds the owners
+	r.Deregister("Etcd")
+	if got, _ := r.LookupJiraComponent("Etcd"); got != "Aggregated" {
+		t.Errorf("LookupJiraComponent(%q) after deregistering its owner = %q, want Aggregated", "Etcd", got)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// metrics counts requests and their latency by handler and status code, and
+// writes them in the Prometheus text exposition format.
+type metrics struct {
+	mu       sync.Mutex
+	requests map[requestKey]*requestStats
+}
+
+type requestKey struct {
+	handler string
+	code    int
+}
+
+type requestStats struct {
+	count   int64
+	seconds float64
+}
+
+func newMetrics() *metrics {
+	return &metrics{requests: make(map[requestKey]*requestStats)}
+}
+
+func (m *metrics) observe(handler string, code int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := requestKey{handler: handler, code: code}
+	stats, ok := m.requests[key]
+	if !ok {
+		stats = &requestStats{}
+		m.requests[key] = stats
+	}
+	stats.count++
+	stats.seconds += duration.Seconds()
+}
+
+func (m *metrics) write(w io.Writer, tests, components int) error {
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.requests))
+	stats := make(map[requestKey]requestStats, len(m.requests))
+	for key, s := range m.requests {
+		keys = append(keys, key)
+		stats[key] = *s
+	}
+	m.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].handler != keys[j].handler {
+			return keys[i].handler < keys[j].handler
+		}
+		return keys[i].code < keys[j].code
+	})
+
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("# HELP ci_test_mapping_http_requests_total Number of HTTP requests served, by handler and status code.\n")
+	printf("# TYPE ci_test_mapping_http_requests_total counter\n")
+	for _, key := range keys {
+		printf("ci_test_mapping_http_requests_total{handler=%q,code=\"%d\"} %d\n", key.handler, key.code, stats[key].count)
+	}
+	printf("# HELP ci_test_mapping_http_request_duration_seconds_total Time spent serving HTTP requests, by handler and status code.\n")
+	printf("# TYPE ci_test_mapping_http_request_duration_seconds_total counter\n")
+	for _, key := range keys {
+		printf("ci_test_mapping_http_request_duration_seconds_total{handler=%q,code=\"%d\"} %g\n", key.handler, key.code, stats[key].seconds)
+	}
+	printf("# HELP ci_test_mapping_tests Number of test mappings loaded.\n")
+	printf("# TYPE ci_test_mapping_tests gauge\n")
+	printf("ci_test_mapping_tests %d\n", tests)
+	printf("# HELP ci_test_mapping_components Number of registered components.\n")
+	printf("# TYPE ci_test_mapping_components gauge\n")
+	printf("ci_test_mapping_components %d\n", components)
+
+	return err
+}
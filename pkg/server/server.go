@@ -0,0 +1,226 @@
+// Package server serves test ownership over an HTTP JSON API, so other tools
+// can ask who owns a test without importing this repository or querying
+// BigQuery.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/components"
+	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
+)
+
+// maxRequestBytes limits the size of a request body. A TestInfo is far
+// smaller.
+const maxRequestBytes = 4 << 20
+
+// Server answers ownership questions using the component registry, for
+// arbitrary tests, and a set of previously computed mappings, such as
+// mapping.json, for known tests. A Server is safe for concurrent use.
+//
+// Endpoints:
+//
+//	GET  /healthz                          liveness
+//	GET  /metrics                          request metrics, in the Prometheus text format
+//	GET  /api/v1/identify?name=&suite=     identify an arbitrary test
+//	POST /api/v1/identify                  identify a test given as a JSON TestInfo
+//	GET  /api/v1/tests/<id>                look up a mapped test by ID
+//	GET  /api/v1/tests?component=&capability=  list mapped tests
+//	GET  /api/v1/components                list components with their Jira components
+type Server struct {
+	reg     *registry.Registry
+	index   *components.Index
+	catalog []registry.ComponentCapabilities
+
+	mappings []v1.TestOwnership
+	byID     map[string][]int
+
+	metrics *metrics
+	mux     *http.ServeMux
+}
+
+// Error is the body of every unsuccessful response.
+type Error struct {
+	Error string `json:"error"`
+	// Conflict is set when identifying a test that has conflicting owners.
+	Conflict *components.ConflictError `json:"conflict,omitempty"`
+}
+
+// New builds a server over the registry and mappings. Records marked
+// StaffApprovedObsolete can be looked up by ID, but aren't listed.
+func New(reg *registry.Registry, mappings []v1.TestOwnership) *Server {
+	s := &Server{
+		reg:      reg,
+		index:    components.NewIndex(reg),
+		catalog:  reg.CapabilityCatalog(),
+		mappings: mappings,
+		byID:     make(map[string][]int),
+		metrics:  newMetrics(),
+		mux:      http.NewServeMux(),
+	}
+	for i := range mappings {
+		s.byID[mappings[i].ID] = append(s.byID[mappings[i].ID], i)
+	}
+
+	s.handle("/healthz", s.healthz)
+	s.handle("/metrics", s.serveMetrics)
+	s.handle("/api/v1/identify", s.identify)
+	s.handle("/api/v1/tests", s.listTests)
+	s.handle("/api/v1/tests/", s.lookupTest)
+	s.handle("/api/v1/components", s.listComponents)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle registers a handler, recording metrics under its pattern.
+func (s *Server) handle(pattern string, handler func(http.ResponseWriter, *http.Request) int) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		status := handler(w, r)
+		s.metrics.observe(pattern, status, time.Since(start))
+		log.WithFields(log.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"status": status,
+		}).Debug("served request")
+	})
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) int {
+	return writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) int {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := s.metrics.write(w, len(s.mappings), len(s.catalog)); err != nil {
+		log.WithError(err).Warning("could not write metrics")
+	}
+	return http.StatusOK
+}
+
+func (s *Server) identify(w http.ResponseWriter, r *http.Request) int {
+	var test v1.TestInfo
+	switch r.Method {
+	case http.MethodGet:
+		test.Name = r.URL.Query().Get("name")
+		test.Suite = r.URL.Query().Get("suite")
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBytes)
+		if err := json.NewDecoder(r.Body).Decode(&test); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return writeError(w, http.StatusRequestEntityTooLarge,
+					fmt.Errorf("test must be at most %d bytes", tooLarge.Limit))
+			}
+			return writeError(w, http.StatusBadRequest, fmt.Errorf("invalid test: %w", err))
+		}
+	default:
+		return writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+	if test.Name == "" {
+		return writeError(w, http.StatusBadRequest, errors.New("test name is required"))
+	}
+
+	ownership, err := s.index.IdentifyTest(&test)
+	var conflict *components.ConflictError
+	if errors.As(err, &conflict) {
+		return writeJSON(w, http.StatusConflict, Error{Error: conflict.Error(), Conflict: conflict})
+	} else if err != nil {
+		return writeError(w, http.StatusInternalServerError, err)
+	}
+	return writeJSON(w, http.StatusOK, ownership)
+}
+
+func (s *Server) lookupTest(w http.ResponseWriter, r *http.Request) int {
+	if r.Method != http.MethodGet {
+		return writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/tests/")
+	indexes, ok := s.byID[id]
+	if id == "" || !ok {
+		return writeError(w, http.StatusNotFound, fmt.Errorf("no test with ID %q", id))
+	}
+
+	// Several records may share an ID when a test was renamed; they're all
+	// returned.
+	records := make([]v1.TestOwnership, 0, len(indexes))
+	for _, i := range indexes {
+		records = append(records, s.mappings[i])
+	}
+	return writeJSON(w, http.StatusOK, records)
+}
+
+func (s *Server) listTests(w http.ResponseWriter, r *http.Request) int {
+	if r.Method != http.MethodGet {
+		return writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+	component := r.URL.Query().Get("component")
+	capability := r.URL.Query().Get("capability")
+	if component == "" && capability == "" {
+		return writeError(w, http.StatusBadRequest, errors.New("component or capability is required"))
+	}
+
+	records := []v1.TestOwnership{}
+	for i := range s.mappings {
+		m := &s.mappings[i]
+		if m.StaffApprovedObsolete {
+			continue
+		}
+		if component != "" && m.Component != component {
+			continue
+		}
+		if capability != "" && !hasCapability(m, capability) {
+			continue
+		}
+		records = append(records, *m)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		return records[i].Suite < records[j].Suite
+	})
+	return writeJSON(w, http.StatusOK, records)
+}
+
+func (s *Server) listComponents(w http.ResponseWriter, r *http.Request) int {
+	if r.Method != http.MethodGet {
+		return writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+	return writeJSON(w, http.StatusOK, s.catalog)
+}
+
+func hasCapability(m *v1.TestOwnership, capability string) bool {
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, err error) int {
+	return writeJSON(w, status, Error{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) int {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.WithError(err).Warning("could not write response")
+	}
+	return status
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/config"
+	"github.com/openshift-eng/ci-test-mapping/pkg/registry"
+)
+
+func newTestServer() *Server {
+	reg := &registry.Registry{}
+	reg.Register("Storage", &config.Component{
+		Name:                 "Storage",
+		DefaultJiraComponent: "Storage",
+		Matchers:             []config.ComponentMatcher{{SIG: "sig-storage"}},
+	})
+	reg.Register("Networking", &config.Component{
+		Name:                 "Networking",
+		DefaultJiraComponent: "Networking",
+		Matchers:             []config.ComponentMatcher{{SIG: "sig-network"}},
+	})
+	reg.Register("Networking / DNS", &config.Component{
+		Name:                 "Networking / DNS",
+		DefaultJiraComponent: "Networking / DNS",
+		Matchers:             []config.ComponentMatcher{{SIG: "sig-network"}},
+	})
+
+	return New(reg, []v1.TestOwnership{
+		{ID: "a", Name: "volumes mount", Suite: "s", Component: "Storage", Capabilities: []string{"Volumes"}},
+		{ID: "b", Name: "snapshots work", Suite: "s", Component: "Storage", Capabilities: []string{"Snapshots"}},
+		{ID: "c", Name: "pods talk", Suite: "s", Component: "Networking", Capabilities: []string{"Other"}},
+		{ID: "d", Name: "old test", Suite: "s", Component: "Storage", Capabilities: []string{"Volumes"}, StaffApprovedObsolete: true},
+	})
+}
+
+func TestServer(t *testing.T) {
+	s := newTestServer()
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		wantStatus int
+		wantNames  []string
+		wantInBody []string
+	}{
+		{
+			name:       "health",
+			url:        "/healthz",
+			wantStatus: http.StatusOK,
+			wantInBody: []string{`"status":"ok"`},
+		},
+		{
+			name:       "identify by query",
+			url:        "/api/v1/identify?name=" + "%5Bsig-storage%5D%20volumes%20mount" + "&suite=s",
+			wantStatus: http.StatusOK,
+			wantInBody: []string{`"Component":"Storage"`, `"Suite":"s"`},
+		},
+		{
+			name:       "identify by body",
+			method:     http.MethodPost,
+			url:        "/api/v1/identify",
+			body:       `{"Name": "[sig-storage] volumes mount", "Suite": "s"}`,
+			wantStatus: http.StatusOK,
+			wantInBody: []string{`"Component":"Storage"`},
+		},
+		{
+			name:       "identify by a body too large",
+			method:     http.MethodPost,
+			url:        "/api/v1/identify",
+			body:       `{"Name": "` + strings.Repeat("a", maxRequestBytes) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "identify unknown test",
+			url:        "/api/v1/identify?name=whatever",
+			wantStatus: http.StatusOK,
+			wantInBody: []string{`"Component":"Unknown"`},
+		},
+		{
+			name:       "identify conflict",
+			url:        "/api/v1/identify?name=%5Bsig-network%5D%20pods%20talk",
+			wantStatus: http.StatusConflict,
+			wantInBody: []string{`"conflict":`, `"Networking / DNS"`},
+		},
+		{
+			name:       "identify without a name",
+			url:        "/api/v1/identify",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "lookup by ID",
+			url:        "/api/v1/tests/b",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"snapshots work"},
+		},
+		{
+			name:       "lookup obsolete test by ID",
+			url:        "/api/v1/tests/d",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"old test"},
+		},
+		{
+			name:       "lookup missing ID",
+			url:        "/api/v1/tests/z",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "list by component",
+			url:        "/api/v1/tests?component=Storage",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"snapshots work", "volumes mount"},
+		},
+		{
+			name:       "list by component and capability",
+			url:        "/api/v1/tests?component=Storage&capability=Volumes",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{"volumes mount"},
+		},
+		{
+			name:       "list by capability with no tests",
+			url:        "/api/v1/tests?capability=Nothing",
+			wantStatus: http.StatusOK,
+			wantNames:  []string{},
+		},
+		{
+			name:       "list without a filter",
+			url:        "/api/v1/tests",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "components",
+			url:        "/api/v1/components",
+			wantStatus: http.StatusOK,
+			wantInBody: []string{`"component":"Networking / DNS","jiraComponents":["Networking / DNS"]`},
+		},
+		{
+			name:       "method not allowed",
+			method:     http.MethodDelete,
+			url:        "/api/v1/tests/a",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(method, tt.url, strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() got status = %d, want %d, body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			for _, want := range tt.wantInBody {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("ServeHTTP() body = %s, want it to contain %s", rec.Body.String(), want)
+				}
+			}
+			if tt.wantNames != nil {
+				var records []v1.TestOwnership
+				if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil {
+					t.Fatalf("could not decode response: %v", err)
+				}
+				names := []string{}
+				for _, r := range records {
+					names = append(names, r.Name)
+				}
+				if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+					t.Errorf("ServeHTTP() got tests = %v, want %v", names, tt.wantNames)
+				}
+			}
+		})
+	}
+}
+
+func TestServerMetrics(t *testing.T) {
+	s := newTestServer()
+	for _, url := range []string{"/healthz", "/healthz", "/api/v1/tests/z"} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`ci_test_mapping_http_requests_total{handler="/healthz",code="200"} 2`,
+		`ci_test_mapping_http_requests_total{handler="/api/v1/tests/",code="404"} 1`,
+		`ci_test_mapping_tests 4`,
+		`ci_test_mapping_components 3`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics = %s, want them to contain %s", rec.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package sources
+
+import (
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+	"github.com/openshift-eng/ci-test-mapping/pkg/bigquery"
+)
+
+// BigQuery lists the tests seen in job results in the BigQuery junit table.
+type BigQuery struct {
+	TestLister bigquery.TestLister
+}
+
+func (s *BigQuery) Name() string {
+	return "bigquery"
+}
+
+func (s *BigQuery) ListTests() ([]v1.TestInfo, error) {
+	return s.TestLister.ListTests()
+}
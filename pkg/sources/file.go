@@ -0,0 +1,79 @@
+package sources
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+// JSONFile reads a JSON list of v1.TestInfo, such as bigquery_tests.json.
+type JSONFile struct {
+	Path string
+}
+
+func (s *JSONFile) Name() string {
+	return "json:" + s.Path
+}
+
+func (s *JSONFile) ListTests() ([]v1.TestInfo, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tests []v1.TestInfo
+	if err := json.Unmarshal(data, &tests); err != nil {
+		return nil, err
+	}
+
+	return tests, nil
+}
+
+// DefaultOpenShiftTestsSuite is the suite assigned to tests read from
+// `openshift-tests` listings.
+const DefaultOpenShiftTestsSuite = "openshift-tests"
+
+// OpenShiftTestsList reads the output of `openshift-tests run --dry-run`,
+// or any other listing with one test name per line. Names may be quoted.
+// Blank lines are skipped. Every test is assigned Suite.
+type OpenShiftTestsList struct {
+	Path  string
+	Suite string
+}
+
+func (s *OpenShiftTestsList) Name() string {
+	return "openshift-tests:" + s.Path
+}
+
+func (s *OpenShiftTestsList) ListTests() ([]v1.TestInfo, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	suite := s.Suite
+	if suite == "" {
+		suite = DefaultOpenShiftTestsSuite
+	}
+
+	var tests []v1.TestInfo
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			name = unquoted
+		}
+		tests = append(tests, v1.TestInfo{Name: name, Suite: suite})
+	}
+
+	return tests, scanner.Err()
+}
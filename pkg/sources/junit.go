@@ -0,0 +1,81 @@
+package sources
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+// JUnitDirectory reads every JUnit XML file (*.xml) under Dir. A test's suite
+// is the name of the innermost <testsuite> containing it. The file attribute
+// of a <testcase>, when present, becomes the test's FilePath.
+type JUnitDirectory struct {
+	Dir string
+}
+
+type junitSuite struct {
+	Name      string       `xml:"name,attr"`
+	Suites    []junitSuite `xml:"testsuite"`
+	TestCases []struct {
+		Name string `xml:"name,attr"`
+		File string `xml:"file,attr"`
+	} `xml:"testcase"`
+}
+
+func (s *JUnitDirectory) Name() string {
+	return "junit:" + s.Dir
+}
+
+func (s *JUnitDirectory) ListTests() ([]v1.TestInfo, error) {
+	var files []string
+	err := filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".xml") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var tests []v1.TestInfo
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		// Files may have either <testsuites> or a single <testsuite> at the
+		// root; both decode into the same structure.
+		var root junitSuite
+		if err := xml.Unmarshal(data, &root); err != nil {
+			return nil, fmt.Errorf("could not parse junit file %s: %w", file, err)
+		}
+		tests = appendJUnitTests(tests, &root)
+	}
+
+	return tests, nil
+}
+
+func appendJUnitTests(tests []v1.TestInfo, suite *junitSuite) []v1.TestInfo {
+	for _, tc := range suite.TestCases {
+		if tc.Name == "" {
+			continue
+		}
+		tests = append(tests, v1.TestInfo{Name: tc.Name, Suite: suite.Name, FilePath: tc.File})
+	}
+	for i := range suite.Suites {
+		tests = appendJUnitTests(tests, &suite.Suites[i])
+	}
+	return tests
+}
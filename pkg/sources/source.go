@@ -0,0 +1,100 @@
+package sources
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+// TestSource provides the list of tests to map.
+type TestSource interface {
+	// Name describes the source, e.g. "json:bigquery_tests.json".
+	Name() string
+
+	// ListTests returns every test known to the source.
+	ListTests() ([]v1.TestInfo, error)
+}
+
+// Merged combines several sources into one. Tests are deduplicated by name
+// and suite; the first source to return a test wins, but metadata it lacks is
+// filled in from later sources.
+type Merged struct {
+	Sources []TestSource
+}
+
+func NewMerged(sources ...TestSource) *Merged {
+	return &Merged{Sources: sources}
+}
+
+func (m *Merged) Name() string {
+	names := make([]string, 0, len(m.Sources))
+	for _, source := range m.Sources {
+		names = append(names, source.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (m *Merged) ListTests() ([]v1.TestInfo, error) {
+	type key struct {
+		name  string
+		suite string
+	}
+
+	var results []v1.TestInfo
+	seen := make(map[key]int)
+	for _, source := range m.Sources {
+		tests, err := source.ListTests()
+		if err != nil {
+			return nil, err
+		}
+
+		added := 0
+		for _, test := range tests {
+			k := key{test.Name, test.Suite}
+			if i, ok := seen[k]; ok {
+				mergeMetadata(&results[i], &test)
+				continue
+			}
+			seen[k] = len(results)
+			results = append(results, test)
+			added++
+		}
+		log.WithFields(log.Fields{
+			"source": source.Name(),
+			"tests":  len(tests),
+			"new":    added,
+		}).Infof("listed tests from source")
+	}
+
+	return results, nil
+}
+
+// mergeMetadata fills the metadata dst is missing from src.
+func mergeMetadata(dst, src *v1.TestInfo) {
+	if dst.Repository == "" {
+		dst.Repository = src.Repository
+	}
+	if dst.FilePath == "" {
+		dst.FilePath = src.FilePath
+	}
+	if len(dst.Labels) == 0 {
+		dst.Labels = src.Labels
+	}
+	if dst.Lifecycle == "" {
+		dst.Lifecycle = src.Lifecycle
+	}
+	if dst.Release == "" {
+		dst.Release = src.Release
+	}
+	for k, v := range src.Annotations {
+		if _, ok := dst.Annotations[k]; ok {
+			continue
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = make(map[string]string)
+		}
+		dst.Annotations[k] = v
+	}
+}
@@ -0,0 +1,100 @@
+package sources
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSources(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tests.json"), `[
+		{"Name": "[sig-storage] a", "Suite": "openshift-tests", "Labels": ["Serial"], "Annotations": {"owner": "storage"}},
+		{"Name": "install should succeed", "Suite": "cluster install"}
+	]`)
+	writeFile(t, filepath.Join(dir, "junit", "e2e", "junit_e2e.xml"), `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="openshift-tests" tests="2">
+    <testcase name="[sig-storage] a" file="test/extended/storage/a.go"></testcase>
+    <testcase name="[sig-network] b"><failure>boom</failure></testcase>
+  </testsuite>
+</testsuites>`)
+	writeFile(t, filepath.Join(dir, "junit", "junit_operator.xml"), `<testsuite name="Operator results">
+  <testcase name="operator conditions etcd"></testcase>
+</testsuite>`)
+	writeFile(t, filepath.Join(dir, "list.txt"), `"[sig-network] b"
+
+"[sig-cli] c"
+`)
+
+	tests := []struct {
+		name   string
+		source TestSource
+		want   []v1.TestInfo
+	}{
+		{
+			name:   "json file",
+			source: &JSONFile{Path: filepath.Join(dir, "tests.json")},
+			want: []v1.TestInfo{
+				{Name: "[sig-storage] a", Suite: "openshift-tests", Labels: []string{"Serial"}, Annotations: map[string]string{"owner": "storage"}},
+				{Name: "install should succeed", Suite: "cluster install"},
+			},
+		},
+		{
+			name:   "junit directory",
+			source: &JUnitDirectory{Dir: filepath.Join(dir, "junit")},
+			want: []v1.TestInfo{
+				{Name: "[sig-storage] a", Suite: "openshift-tests", FilePath: "test/extended/storage/a.go"},
+				{Name: "[sig-network] b", Suite: "openshift-tests"},
+				{Name: "operator conditions etcd", Suite: "Operator results"},
+			},
+		},
+		{
+			name:   "openshift-tests listing",
+			source: &OpenShiftTestsList{Path: filepath.Join(dir, "list.txt")},
+			want: []v1.TestInfo{
+				{Name: "[sig-network] b", Suite: "openshift-tests"},
+				{Name: "[sig-cli] c", Suite: "openshift-tests"},
+			},
+		},
+		{
+			name: "merged sources are deduplicated and their metadata combined",
+			source: NewMerged(
+				&JSONFile{Path: filepath.Join(dir, "tests.json")},
+				&JUnitDirectory{Dir: filepath.Join(dir, "junit")},
+				&OpenShiftTestsList{Path: filepath.Join(dir, "list.txt")},
+			),
+			want: []v1.TestInfo{
+				{Name: "[sig-storage] a", Suite: "openshift-tests", FilePath: "test/extended/storage/a.go", Labels: []string{"Serial"}, Annotations: map[string]string{"owner": "storage"}},
+				{Name: "install should succeed", Suite: "cluster install"},
+				{Name: "[sig-network] b", Suite: "openshift-tests"},
+				{Name: "operator conditions etcd", Suite: "Operator results"},
+				{Name: "[sig-cli] c", Suite: "openshift-tests"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.source.ListTests()
+			if err != nil {
+				t.Fatalf("ListTests() returned unexpected err: %+v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListTests() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package util
+
+// SubstringSet finds which of a fixed set of substrings occur in a string,
+// in a single pass over the string, using the Aho-Corasick algorithm.
+type SubstringSet struct {
+	nodes    []acNode
+	patterns int
+	// empty lists the patterns that are the empty string, which occur in
+	// every string.
+	empty []int
+}
+
+type acNode struct {
+	next map[byte]int32
+	fail int32
+	// out lists the patterns ending at this node; dict is the nearest node
+	// along the failure links with a non-empty out, or -1.
+	out  []int
+	dict int32
+}
+
+// NewSubstringSet builds a SubstringSet. Patterns are identified by their
+// index in the slice.
+func NewSubstringSet(patterns []string) *SubstringSet {
+	s := &SubstringSet{
+		nodes:    []acNode{{dict: -1}},
+		patterns: len(patterns),
+	}
+
+	for id, pattern := range patterns {
+		if pattern == "" {
+			s.empty = append(s.empty, id)
+			continue
+		}
+		node := int32(0)
+		for i := 0; i < len(pattern); i++ {
+			child, ok := s.nodes[node].next[pattern[i]]
+			if !ok {
+				child = int32(len(s.nodes))
+				s.nodes = append(s.nodes, acNode{dict: -1})
+				if s.nodes[node].next == nil {
+					s.nodes[node].next = make(map[byte]int32)
+				}
+				s.nodes[node].next[pattern[i]] = child
+			}
+			node = child
+		}
+		s.nodes[node].out = append(s.nodes[node].out, id)
+	}
+
+	// Breadth-first, so a node's failure link is computed before its
+	// children's.
+	queue := make([]int32, 0, len(s.nodes))
+	for _, child := range s.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for b, child := range s.nodes[node].next {
+			fail := s.nodes[node].fail
+			for {
+				if next, ok := s.nodes[fail].next[b]; ok && next != child {
+					s.nodes[child].fail = next
+					break
+				}
+				if fail == 0 {
+					s.nodes[child].fail = 0
+					break
+				}
+				fail = s.nodes[fail].fail
+			}
+			if f := s.nodes[child].fail; len(s.nodes[f].out) > 0 {
+				s.nodes[child].dict = f
+			} else {
+				s.nodes[child].dict = s.nodes[f].dict
+			}
+			queue = append(queue, child)
+		}
+	}
+
+	return s
+}
+
+// Find calls found once for every pattern occurring in str, in no particular
+// order.
+func (s *SubstringSet) Find(str string, found func(pattern int)) {
+	seen := make([]bool, s.patterns)
+	report := func(ids []int) {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				found(id)
+			}
+		}
+	}
+
+	report(s.empty)
+	node := int32(0)
+	for i := 0; i < len(str); i++ {
+		for {
+			if next, ok := s.nodes[node].next[str[i]]; ok {
+				node = next
+				break
+			}
+			if node == 0 {
+				break
+			}
+			node = s.nodes[node].fail
+		}
+		report(s.nodes[node].out)
+		for d := s.nodes[node].dict; d >= 0; d = s.nodes[d].dict {
+			report(s.nodes[d].out)
+		}
+	}
+}
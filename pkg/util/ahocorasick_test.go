@@ -0,0 +1,85 @@
+package util
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSubstringSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		str      string
+		want     []int
+	}{
+		{
+			name:     "overlapping patterns",
+			patterns: []string{"he", "she", "his", "hers"},
+			str:      "ushers",
+			want:     []int{0, 1, 3},
+		},
+		{
+			name:     "pattern that is a suffix of another",
+			patterns: []string{"network", "work", "networking"},
+			str:      "[sig-network] works",
+			want:     []int{0, 1},
+		},
+		{
+			name:     "empty and duplicate patterns",
+			patterns: []string{"", "etcd", "etcd", "apiserver"},
+			str:      "etcd leader changes",
+			want:     []int{0, 1, 2},
+		},
+		{
+			name:     "no patterns",
+			patterns: nil,
+			str:      "anything",
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			NewSubstringSet(tt.patterns).Find(tt.str, func(pattern int) {
+				got = append(got, pattern)
+			})
+			sort.Ints(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubstringSetMatchesContains(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomString := func(n int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			b.WriteByte("abc"[r.Intn(3)])
+		}
+		return b.String()
+	}
+
+	var patterns []string
+	for i := 0; i < 200; i++ {
+		patterns = append(patterns, randomString(1+r.Intn(5)))
+	}
+	set := NewSubstringSet(patterns)
+
+	for i := 0; i < 500; i++ {
+		str := randomString(r.Intn(30))
+		found := make(map[int]bool)
+		set.Find(str, func(pattern int) {
+			found[pattern] = true
+		})
+		for id, pattern := range patterns {
+			if want := strings.Contains(str, pattern); found[id] != want {
+				t.Fatalf("Find(%q) found pattern %q = %v, want %v", str, pattern, found[id], want)
+			}
+		}
+	}
+}
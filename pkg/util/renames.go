@@ -0,0 +1,44 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RenameMapper builds a StableID mapper from a table of test renames, keyed by
+// the old test name with the new name as the value. The mapper returns the
+// original name of a renamed test, and any other name unchanged.
+//
+// The table must be flat: a new name may not itself appear as an old name,
+// which rules out both chains (a->b, b->c) and cycles, and two old names may
+// not be renamed to the same new name. When a renamed test is renamed again,
+// update the existing entry's value instead of adding another one.
+func RenameMapper(renames map[string]string) (func(string) string, error) {
+	oldNames := make([]string, 0, len(renames))
+	for oldName := range renames {
+		oldNames = append(oldNames, oldName)
+	}
+	sort.Strings(oldNames)
+
+	original := make(map[string]string, len(renames))
+	for _, oldName := range oldNames {
+		newName := renames[oldName]
+		if newName == "" {
+			return nil, fmt.Errorf("rename of %q has an empty new name", oldName)
+		}
+		if _, ok := renames[newName]; ok {
+			return nil, fmt.Errorf("rename of %q to %q is chained or cyclic: %q is renamed as well", oldName, newName, newName)
+		}
+		if other, ok := original[newName]; ok {
+			return nil, fmt.Errorf("both %q and %q are renamed to %q", other, oldName, newName)
+		}
+		original[newName] = oldName
+	}
+
+	return func(name string) string {
+		if oldName, ok := original[name]; ok {
+			return oldName
+		}
+		return name
+	}, nil
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
+)
+
+func TestRenameMapper(t *testing.T) {
+	tests := []struct {
+		name      string
+		renames   map[string]string
+		testInfo  *v1.TestInfo
+		wantID    string
+		wantError string
+	}{
+		{
+			name:     "renamed test keeps its original ID",
+			renames:  map[string]string{"old name": "new name"},
+			testInfo: &v1.TestInfo{Name: "new name", Suite: "openshift-tests"},
+			wantID:   "openshift-tests.old name",
+		},
+		{
+			name:     "other tests are unchanged",
+			renames:  map[string]string{"old name": "new name"},
+			testInfo: &v1.TestInfo{Name: "another name", Suite: "openshift-tests"},
+			wantID:   "openshift-tests.another name",
+		},
+		{
+			name:      "rejects chains",
+			renames:   map[string]string{"a": "b", "b": "c"},
+			wantError: "chained or cyclic",
+		},
+		{
+			name:      "rejects cycles",
+			renames:   map[string]string{"a": "b", "b": "a"},
+			wantError: "chained or cyclic",
+		},
+		{
+			name:      "rejects self renames",
+			renames:   map[string]string{"a": "a"},
+			wantError: "chained or cyclic",
+		},
+		{
+			name:      "rejects ambiguous renames",
+			renames:   map[string]string{"a": "c", "b": "c"},
+			wantError: "are renamed to",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper, err := RenameMapper(tt.renames)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("RenameMapper() error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenameMapper() returned unexpected err: %+v", err)
+			}
+			if got := StableID(tt.testInfo, mapper); got != tt.wantID {
+				t.Errorf("StableID() = %q, want %q", got, tt.wantID)
+			}
+		})
+	}
+}
@@ -37,6 +37,20 @@ func IdentifyOperatorTest(operator, testName string) (isOperatorTest bool, capab
 	return false, nil
 }
 
+// OperatorTestNames returns the operator names a test name refers to, such
+// that IdentifyOperatorTest(operator, testName) is true exactly for the
+// operators in the result.
+func OperatorTestNames(testName string) []string {
+	var names []string
+	for _, re := range []*regexp.Regexp{conditions, upgradeRegex, installRegex, imageBuild} {
+		if matches := re.FindStringSubmatch(testName); len(matches) > 1 {
+			names = append(names, matches[1])
+		}
+	}
+
+	return names
+}
+
 func matchOne(re *regexp.Regexp, testName, match string) bool {
 	matches := re.FindStringSubmatch(testName)
 	if len(matches) > 1 && matches[1] == match {
@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	v1 "github.com/openshift-eng/ci-test-mapping/pkg/api/types/v1"
@@ -23,6 +24,21 @@ func ExtractTestField(testName, field string) (results []string) {
 	return results
 }
 
+// ExtractJiraComponent returns the Jira component named by a [Jira:"Component"] annotation in a
+// test name, if any. The quotes are optional. When a test has several annotations, the first wins.
+func ExtractJiraComponent(testName string) (string, bool) {
+	for _, value := range ExtractTestField(testName, "Jira") {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = strings.TrimSpace(unquoted)
+		}
+		if value != "" {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
 // StableID produces a stable test ID based on a TestInfo struct, and a stable name mapping function.
 // The mapper function can be used to handle test renames.
 func StableID(testInfo *v1.TestInfo, mapper func(string) string) string {
@@ -45,3 +45,49 @@ func TestExtractField(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractJiraComponent(t *testing.T) {
+	tests := []struct {
+		name      string
+		test      string
+		want      string
+		wantFound bool
+	}{
+		{
+			name:      "quoted component",
+			test:      `[sig-network-edge][Jira:"Networking / router"] router should work [Suite:openshift/conformance/parallel]`,
+			want:      "Networking / router",
+			wantFound: true,
+		},
+		{
+			name:      "unquoted component",
+			test:      `[sig-etcd][Jira: Etcd] etcd should work`,
+			want:      "Etcd",
+			wantFound: true,
+		},
+		{
+			name:      "first annotation wins",
+			test:      `[Jira:"Etcd"][Jira:"kube-apiserver"] etcd should work`,
+			want:      "Etcd",
+			wantFound: true,
+		},
+		{
+			name:      "empty annotation",
+			test:      `[Jira:""] etcd should work`,
+			wantFound: false,
+		},
+		{
+			name:      "no annotation",
+			test:      `[sig-etcd] etcd should work`,
+			wantFound: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := ExtractJiraComponent(tt.test)
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("ExtractJiraComponent() = %q, %v, want %q, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package version identifies the build of ci-test-mapping that produced a
+// mapping snapshot.
+package version
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// Version and Commit can be set at build time with
+//
+//	-ldflags "-X github.com/openshift-eng/ci-test-mapping/pkg/version.Version=..."
+//
+// Otherwise they're read from the build info Go embeds in the binary.
+var (
+	Version string
+	Commit  string
+)
+
+// Unknown is reported for a version or commit that can't be determined.
+const Unknown = "unknown"
+
+// DirtySuffix is appended to the commit of a build with uncommitted changes.
+const DirtySuffix = "-dirty"
+
+// Get returns the version and git commit of the running binary. Either may
+// be "unknown", e.g. under go run, where no VCS information is embedded.
+func Get() (version, commit string) {
+	version, commit = Version, Commit
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+		if commit == "" {
+			var revision string
+			var modified bool
+			for _, setting := range info.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					revision = setting.Value
+				case "vcs.modified":
+					modified = setting.Value == "true"
+				}
+			}
+			// A build with uncommitted changes isn't the code at its commit
+			if revision != "" && modified {
+				revision += DirtySuffix
+			}
+			commit = revision
+		}
+	}
+	if version == "" {
+		version = Unknown
+	}
+	if commit == "" {
+		commit = Unknown
+	}
+	return version, commit
+}
+
+// Identifies reports whether a commit identifies the code a build was made
+// from. An unknown commit doesn't, and neither does a dirty one, which stays
+// the same however the uncommitted changes are edited.
+func Identifies(commit string) bool {
+	return commit != Unknown && !strings.HasSuffix(commit, DirtySuffix)
+}
@@ -0,0 +1,31 @@
+package version
+
+import "testing"
+
+func TestGet(t *testing.T) {
+	Version, Commit = "v1.2.3", "abc123"
+	defer func() { Version, Commit = "", "" }()
+
+	version, commit := Get()
+	if version != "v1.2.3" || commit != "abc123" {
+		t.Errorf("Get() = %q, %q, want the values set at build time", version, commit)
+	}
+}
+
+func TestIdentifies(t *testing.T) {
+	tests := []struct {
+		commit string
+		want   bool
+	}{
+		{commit: "abc123", want: true},
+		{commit: "abc123-dirty", want: false},
+		{commit: "unknown", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.commit, func(t *testing.T) {
+			if got := Identifies(tt.commit); got != tt.want {
+				t.Errorf("Identifies(%q) = %v, want %v", tt.commit, got, tt.want)
+			}
+		})
+	}
+}